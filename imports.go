@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stdImports are the packages the template may refer to
+var stdImports = map[string]string{
+	"context": "context",
+	"errors":  "errors",
+	"fmt":     "fmt",
+	"rand":    "math/rand",
+	"sql":     "database/sql",
+	"strings": "strings",
+	"time":    "time",
+	"atomic":  "sync/atomic",
+	"sync":    "sync",
+	"sort":    "sort",
+	"io":      "io",
+	"http":    "net/http",
+	"list":    "container/list",
+	"reflect": "reflect",
+	"fnv":     "hash/fnv",
+	"driver":  "database/sql/driver",
+	"regexp":  "regexp",
+}
+
+// fixImports adds the imports used by the generated src, taking them from
+// stdImports or from the imports of the annotated file, and formats it
+func fixImports(src []byte, file *ast.File) ([]byte, error) {
+	fs := token.NewFileSet()
+	generated, err := parser.ParseFile(fs, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	ast.Inspect(generated, func(node ast.Node) bool {
+		selector, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if ident, ok := selector.X.(*ast.Ident); ok && ident.Obj == nil {
+			used[ident.Name] = true
+		}
+		return true
+	})
+	var imports, thirdPartyImports []string
+	for pkgName := range used {
+		importPath := findImport(file, pkgName)
+		if importPath == "" {
+			importPath = stdImports[pkgName]
+		}
+		if importPath == "" {
+			continue
+		}
+		importSpec := strconv.Quote(importPath)
+		if path.Base(importPath) != pkgName {
+			importSpec = pkgName + " " + importSpec
+		}
+		if strings.Contains(strings.Split(importPath, "/")[0], ".") {
+			thirdPartyImports = append(thirdPartyImports, importSpec)
+			continue
+		}
+		imports = append(imports, importSpec)
+	}
+	sort.Strings(imports)
+	sort.Strings(thirdPartyImports)
+	if len(thirdPartyImports) != 0 {
+		imports = append(append(imports, ""), thirdPartyImports...)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	idx := bytes.IndexByte(src, '\n')
+	buf.Write(src[:idx+1])
+	buf.WriteString("\nimport (\n")
+	for _, each := range imports {
+		buf.WriteString(each + "\n")
+	}
+	buf.WriteString(")\n")
+	buf.Write(src[idx+1:])
+	return format.Source(buf.Bytes())
+}
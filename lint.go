@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// lintDir reports every Raw call under dir whose cond is not a constant string
+func lintDir(dir string) ([]string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	// the source importer resolves imports with go list run in build.Default.Dir,
+	// which has to be inside the module of dir
+	build.Default.Dir = absDir
+	fs := token.NewFileSet()
+	pkgs := make(map[string][]*ast.File)
+	var keys []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fs, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		key := filepath.Dir(path) + ":" + file.Name.Name
+		if _, ok := pkgs[key]; !ok {
+			keys = append(keys, key)
+		}
+		pkgs[key] = append(pkgs[key], file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	var issues []string
+	for _, key := range keys {
+		issues = append(issues, lintFiles(fs, pkgs[key])...)
+	}
+	return issues, nil
+}
+
+// lintFiles type checks the files of one package, so that constants declared
+// in other files or imported from other packages are known, and lints them
+func lintFiles(fs *token.FileSet, files []*ast.File) []string {
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	conf := &types.Config{
+		Importer: importer.ForCompiler(fs, "source", nil),
+		// the errors of unresolvable identifiers leave their calls flagged
+		Error: func(err error) {},
+	}
+	conf.Check(files[0].Name.Name, fs, files, info)
+	var issues []string
+	for _, file := range files {
+		issues = append(issues, lintFile(fs, file, info)...)
+	}
+	return issues
+}
+
+func lintFile(fs *token.FileSet, file *ast.File, info *types.Info) []string {
+	var issues []string
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 || !isRawCall(call) {
+			return true
+		}
+		if !isConstString(info, call.Args[0]) {
+			issues = append(issues, fmt.Sprintf("%s: Raw called with non-literal cond", fs.Position(call.Pos())))
+		}
+		return true
+	})
+	return issues
+}
+
+func isRawCall(call *ast.CallExpr) bool {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name == "Raw"
+	case *ast.SelectorExpr:
+		return fun.Sel.Name == "Raw"
+	}
+	return false
+}
+
+func isConstString(info *types.Info, expr ast.Expr) bool {
+	tv, ok := info.Types[expr]
+	return ok && tv.Value != nil && tv.Value.Kind() == constant.String
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintFile(t *testing.T) {
+	srcs := map[string]string{
+		"go.mod": "module example.com/p\n",
+		"p.go": `package p
+
+import "example.com/p/conds"
+
+func Raw(cond string, args ...interface{}) {}
+
+func f(name, input string) {
+	Raw("name=?", name)
+	Raw("name=? " + "and id=?", name, 1)
+	Raw(cond, name)
+	conds.Raw(conds.Cond, name)
+	Raw("name='" + input + "'")
+	conds.Raw(input)
+}
+`,
+		"cond.go": `package p
+
+const cond = "name=?"
+`,
+		"conds/conds.go": `package conds
+
+const Cond = "name=?"
+
+func Raw(cond string, args ...interface{}) {}
+`,
+	}
+	dir, err := ioutil.TempDir("", "lint")
+	if err != nil {
+		t.Fatalf("failed to create dir,err: %#v\r\n", err)
+	}
+	defer os.RemoveAll(dir)
+	for name, src := range srcs {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir,err: %#v\r\n", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatalf("failed to write src,err: %#v\r\n", err)
+		}
+	}
+	issues, err := lintDir(dir)
+	if err != nil {
+		t.Fatalf("failed to lint dir,err: %#v\r\n", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("unexpected issues: %#v\r\n", issues)
+	}
+	if want := filepath.Join(dir, "p.go") + ":12:2: Raw called with non-literal cond"; issues[0] != want {
+		t.Fatalf("unexpected issue: %s\r\n", issues[0])
+	}
+}
@@ -12,19 +12,22 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"os"
 	"path"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
 type tpl struct {
-	PaginateFindSQL   string
 	Name              string
 	FindSQL           string
-	DeleteSQL         string
-	UpdateSQL         string
-	CreateSQL         string
+	Columns           string
+	SaveSQL           string
+	SaveValue         string
+	CreateColumns     string
+	InsertColumns     string
 	ColumnCount       int
 	CreatePlaceHolder string
 	PlaceHolder       string
@@ -32,30 +35,74 @@ type tpl struct {
 	CreateValue       string
 	Scan              string
 	Fields            []*tplField
+	Relations         []*tplRelation
+	SoftDelete        *tplField
+	Timestamps        []*tplField
+	Version           *tplField
+	Tenant            *tplField
 	Lt                string
 	Bt                string
 	Tablename         string
 }
 
 type tplField struct {
-	Name   string
-	Type   string
-	Column string
+	Name           string
+	Type           string
+	Column         string
+	Numeric        bool
+	AutoCreateTime bool
+	AutoUpdateTime bool
+	Sensitive      bool
+}
+
+type tplRelation struct {
+	Name      string
+	Type      string
+	BelongsTo bool
+	HasMany   bool
+	// ForeignKey is the field holding the foreign key, on this struct for
+	// belongs_to and on the related struct for has_many
+	ForeignKey     *tplField
+	ForeignKeyType string
+	// Key is the field the foreign key refers to, on the related struct for
+	// belongs_to and on this struct for has_many
+	Key      *tplField
+	Related  *tpl
+	JoinCond string
 }
 
+type relationResolver func(typ ast.Expr) (string, *tpl)
+
 var (
 	src    string
 	suffix = "_gorm.go"
 	name   string
+	lint   string
+	uow    bool
 )
 
 func init() {
 	flag.StringVar(&src, "src", ".", "-src=testdata/testdata.go")
 	flag.StringVar(&name, "name", ".", "-name=User")
-	flag.Parse()
+	flag.StringVar(&lint, "lint", "", "-lint=.")
+	flag.BoolVar(&uow, "uow", false, "-uow -name=UnitOfWork")
 }
 
 func main() {
+	flag.Parse()
+	if lint != "" {
+		issues, err := lintDir(lint)
+		if err != nil {
+			log.Fatalf("failed to lint dir:%s, err:%#v", lint, err)
+		}
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+		if len(issues) != 0 {
+			os.Exit(1)
+		}
+		return
+	}
 	if name == "" {
 		flag.PrintDefaults()
 		return
@@ -70,15 +117,58 @@ func main() {
 	if err != nil {
 		return
 	}
-	// name := path.Base(src)
 
 	buf := bytes.NewBuffer(nil)
 
 	fullPath := strings.Replace(src, ".go", suffix, -1)
-	var lastGen *ast.GenDecl
 
-	var tpl *tpl
+	st, doc := findStruct(file, name)
+	if st == nil {
+		return
+	}
+	var data interface{}
+	text := tplStr
+	if uow {
+		data = genUnitOfWork(name, st)
+		text = uowTplStr
+	} else {
+		tpl := gen(name, getTableName(name, doc), st, newRelationResolver(fs, file, baseDir))
+		if tpl == nil {
+			return
+		}
+		data = tpl
+	}
+
+	io.WriteString(buf, fmt.Sprintf("package %s\n", p.Name))
+
+	t, err := template.New("gorm").Funcs(template.FuncMap{
+		"raw": raw,
+	}).Parse(text)
+	if err != nil {
+		return
+	}
+	if err := t.Execute(buf, data); err != nil {
+		return
+	}
+	if buf.Len() == 0 {
+		return
+	}
+	result, err := fixImports(buf.Bytes(), file)
+	if err != nil {
+		log.Fatalf("failed to format result, err:%v", err)
+	}
+	ioutil.WriteFile(fullPath, result, 0644)
+}
+
+// findStruct returns the struct named structName in file and its doc
+func findStruct(file *ast.File, structName string) (*ast.StructType, *ast.CommentGroup) {
+	var lastGen *ast.GenDecl
+	var result *ast.StructType
+	var resultDoc *ast.CommentGroup
 	ast.Walk(walker(func(node ast.Node) bool {
+		if result != nil {
+			return false
+		}
 		switch v := node.(type) {
 		case *ast.GenDecl:
 			if v.Tok == token.IMPORT {
@@ -87,22 +177,21 @@ func main() {
 			lastGen = v
 			return true
 		case *ast.TypeSpec:
-			structName := v.Name.Name
-
-			tableName := getTableName(structName, v.Doc, lastGen)
+			doc := v.Doc
+			gen := lastGen
 			lastGen = nil
-			if tableName == "" {
+			if v.Name.Name != structName {
 				return false
 			}
-			if structName != name {
-				return true
-			}
-
 			st, ok := v.Type.(*ast.StructType)
 			if !ok {
-				return true
+				return false
 			}
-			tpl = gen(structName, tableName, buf, st)
+			if doc == nil && gen != nil {
+				doc = gen.Doc
+			}
+			resultDoc = doc
+			result = st
 			return false
 		case *ast.ValueSpec:
 			return false
@@ -111,38 +200,10 @@ func main() {
 			return true
 		}
 	}), file)
-
-	if tpl == nil {
-		return
-	}
-
-	importStr := fmt.Sprintf(`package %s
-	import(
-		"database/sql"
-		"context"
-		"fmt"
-		"strings"
-	)`, p.Name)
-	io.WriteString(buf, importStr)
-
-	t, err := template.New("gorm").Funcs(template.FuncMap{
-		"raw": raw,
-	}).Parse(tplStr)
-	if err != nil {
-		return
-	}
-	if err := t.Execute(buf, tpl); err != nil {
-		return
-	}
-	if buf.Len() != 0 {
-		ioutil.WriteFile(fullPath, buf.Bytes(), 0644)
-	}
+	return result, resultDoc
 }
 
-func getTableName(structName string, doc *ast.CommentGroup, lastGen *ast.GenDecl) string {
-	if doc == nil && lastGen != nil {
-		doc = lastGen.Doc
-	}
+func getTableName(structName string, doc *ast.CommentGroup) string {
 	var comment string
 	if doc != nil && len(doc.List) > 0 {
 		comment = doc.List[0].Text
@@ -155,21 +216,128 @@ func getTableName(structName string, doc *ast.CommentGroup, lastGen *ast.GenDecl
 	panic("no table comment")
 }
 
-func gen(structName, tableName string, buf *bytes.Buffer, st *ast.StructType) *tpl {
+// newRelationResolver resolves related structs declared in the same
+// package as file or in one of the packages it imports
+func newRelationResolver(fs *token.FileSet, file *ast.File, baseDir string) relationResolver {
+	return func(typ ast.Expr) (string, *tpl) {
+		pkgName, structName := exprType(typ)
+		if structName == "" {
+			panic("unsupported relation type")
+		}
+		dir := baseDir
+		typName := structName
+		if pkgName != "" {
+			typName = pkgName + "." + structName
+			importPath := findImport(file, pkgName)
+			if importPath == "" {
+				panic(fmt.Sprintf("no import for relation type %s", typName))
+			}
+			p, err := build.Import(importPath, baseDir, build.FindOnly)
+			if err != nil {
+				panic(fmt.Sprintf("failed to import %s, err:%#v", importPath, err))
+			}
+			dir = p.Dir
+		}
+		pkgs, err := parser.ParseDir(fs, dir, func(info os.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go") && !strings.HasSuffix(info.Name(), suffix)
+		}, parser.ParseComments)
+		if err != nil {
+			panic(fmt.Sprintf("failed to parse dir:%s, err:%#v", dir, err))
+		}
+		for _, pkg := range pkgs {
+			for _, each := range pkg.Files {
+				st, doc := findStruct(each, structName)
+				if st != nil {
+					return typName, gen(structName, getTableName(structName, doc), st, nil)
+				}
+			}
+		}
+		panic(fmt.Sprintf("no relation type %s", typName))
+	}
+}
+
+func findImport(file *ast.File, pkgName string) string {
+	for _, imp := range file.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		if imp.Name != nil {
+			if imp.Name.Name == pkgName {
+				return importPath
+			}
+			continue
+		}
+		if path.Base(importPath) == pkgName {
+			return importPath
+		}
+	}
+	return ""
+}
+
+// exprType returns the package and type name of an optionally pointer,
+// optionally package qualified type expression
+func exprType(expr ast.Expr) (string, string) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch v := expr.(type) {
+	case *ast.Ident:
+		return "", v.Name
+	case *ast.SelectorExpr:
+		pkg, ok := v.X.(*ast.Ident)
+		if !ok {
+			return "", ""
+		}
+		return pkg.Name, v.Sel.Name
+	}
+	return "", ""
+}
+
+func parseTag(tag *ast.BasicLit) (string, map[string]string) {
+	trimedValue := strings.Trim(tag.Value, "`")
+	parts := strings.Split(reflect.StructTag(trimedValue).Get("gorm"), ",")
+	tagOptions := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 2 {
+			tagOptions[kv[0]] = kv[1]
+			continue
+		}
+		tagOptions[kv[0]] = ""
+	}
+	return parts[0], tagOptions
+}
+
+func gen(structName, tableName string, st *ast.StructType, resolve relationResolver) *tpl {
 	fields := st.Fields.List
 	if len(fields) == 0 {
 		return nil
 	}
 	scan := make([]string, 0, len(fields))
 	column := make([]string, 0, len(fields))
+	qualifiedColumn := make([]string, 0, len(fields))
 	value := make([]string, 0, len(fields))
 	placeHolder := make([]string, 0, len(fields))
 	tplFields := make([]*tplField, 0, len(fields))
-	idx := 0
+	relations := make(map[string]*tplRelation)
+	var softDelete *tplField
+	var timestamps []*tplField
+	var version *tplField
+	var tenant *tplField
 	for _, field := range fields {
 		if field.Tag == nil {
 			continue
 		}
+		curColumn, tagOptions := parseTag(field.Tag)
+		name := field.Names[0].Name
+		if _, ok := tagOptions["has_many"]; ok {
+			if resolve != nil {
+				relations[name] = &tplRelation{
+					Name:    name,
+					HasMany: true,
+					Key:     &tplField{Column: curColumn},
+				}
+			}
+			continue
+		}
 		var typ string
 		ident, ok := field.Type.(*ast.Ident)
 		if !ok {
@@ -191,31 +359,121 @@ func gen(structName, tableName string, buf *bytes.Buffer, st *ast.StructType) *t
 			typ = ident.Name
 		}
 
-		trimedValue := strings.Trim(field.Tag.Value, "`")
-		curColumn := reflect.StructTag(trimedValue).Get("gorm")
-		column2Append := curColumn
-		if idx == 0 {
-			column2Append = tableName + "." + curColumn
+		_, sensitive := tagOptions["sensitive"]
+		column = append(column, curColumn)
+		qualifiedColumn = append(qualifiedColumn, tableName+"."+curColumn)
+		if sensitive {
+			value = append(value, "Redact(obj."+name+")")
+		} else {
+			value = append(value, "obj."+name)
 		}
-		column = append(column, column2Append)
-		name := field.Names[0].Name
-		value = append(value, "obj."+name)
 		scan = append(scan, `&result.`+name)
 		placeHolder = append(placeHolder, "?")
-		tplFields = append(tplFields, &tplField{
-			Name:   name,
-			Type:   typ,
-			Column: curColumn,
-		})
-		idx++
+		tplField := &tplField{
+			Name:      name,
+			Type:      typ,
+			Column:    curColumn,
+			Numeric:   isNumeric(typ),
+			Sensitive: sensitive,
+		}
+		tplFields = append(tplFields, tplField)
+		if _, ok := tagOptions["softdelete"]; ok {
+			softDelete = tplField
+		}
+		_, tplField.AutoCreateTime = tagOptions["autocreatetime"]
+		_, tplField.AutoUpdateTime = tagOptions["autoupdatetime"]
+		if (tplField.AutoCreateTime || tplField.AutoUpdateTime) && typ != "time.Time" {
+			panic(fmt.Sprintf("auto managed timestamp %s must be time.Time", name))
+		}
+		if tplField.AutoCreateTime || tplField.AutoUpdateTime {
+			timestamps = append(timestamps, tplField)
+		}
+		if _, ok := tagOptions["version"]; ok {
+			if !tplField.Numeric {
+				panic(fmt.Sprintf("version %s must be numeric", name))
+			}
+			version = tplField
+		}
+		if _, ok := tagOptions["tenant"]; ok {
+			tenant = tplField
+		}
+		if relationName, ok := tagOptions["belongs_to"]; ok && resolve != nil {
+			relations[relationName] = &tplRelation{
+				Name:       relationName,
+				BelongsTo:  true,
+				ForeignKey: tplField,
+			}
+		}
+	}
+	var tplRelations []*tplRelation
+	for _, field := range fields {
+		if len(field.Names) == 0 {
+			continue
+		}
+		relation, ok := relations[field.Names[0].Name]
+		if !ok {
+			continue
+		}
+		typ := field.Type
+		if array, ok := typ.(*ast.ArrayType); ok {
+			typ = array.Elt
+		}
+		relation.Type, relation.Related = resolve(typ)
+		if relation.BelongsTo {
+			relation.Key = relation.Related.Fields[0]
+			relation.ForeignKeyType = relation.ForeignKey.Type
+			relation.JoinCond = fmt.Sprintf("%s.%s = %s.%s", tableName, relation.ForeignKey.Column, relation.Related.Tablename, relation.Key.Column)
+		} else {
+			relation.ForeignKey = findField(relation.Related.Fields, relation.Key.Column)
+			if relation.ForeignKey == nil {
+				panic(fmt.Sprintf("no column %s in %s", relation.Key.Column, relation.Type))
+			}
+			relation.Key = tplFields[0]
+			relation.ForeignKeyType = relation.Key.Type
+			relation.JoinCond = fmt.Sprintf("%s.%s = %s.%s", relation.Related.Tablename, relation.ForeignKey.Column, tableName, relation.Key.Column)
+		}
+		if relation.Related.SoftDelete != nil {
+			relation.JoinCond += fmt.Sprintf(" and %s.%s is null", relation.Related.Tablename, relation.Related.SoftDelete.Column)
+		}
+		if relation.Related.Tenant != nil {
+			if tenant == nil {
+				panic(fmt.Sprintf("relation %s relates the tenant entity %s, %s must be a tenant entity too", relation.Name, relation.Type, structName))
+			}
+			relation.JoinCond += fmt.Sprintf(" and %s.%s = %s.%s", relation.Related.Tablename, relation.Related.Tenant.Column, tableName, tenant.Column)
+		}
+		tplRelations = append(tplRelations, relation)
+	}
+	if len(tplRelations) != len(relations) {
+		panic("no field for relation")
+	}
+	saveColumn := make([]string, 0, len(column))
+	saveValue := make([]string, 0, len(value))
+	for i, each := range tplFields[1:] {
+		if each == version {
+			continue
+		}
+		saveColumn = append(saveColumn, each.Column+"=?")
+		saveValue = append(saveValue, value[i+1])
+	}
+	saveCond := column[0] + "=?"
+	saveValue = append(saveValue, value[0])
+	if version != nil {
+		saveColumn = append(saveColumn, fmt.Sprintf("%s=%s+1", version.Column, version.Column))
+		saveCond += fmt.Sprintf(" and %s=?", version.Column)
+		saveValue = append(saveValue, "obj."+version.Name)
+	}
+	if tenant != nil {
+		saveCond += fmt.Sprintf(" and %s=?", tenant.Column)
+		saveValue = append(saveValue, "obj."+tenant.Name)
 	}
 	return &tpl{
 		Name:              structName,
-		FindSQL:           fmt.Sprintf("select %s from %s", strings.Join(column, ","), tableName),
-		PaginateFindSQL:   fmt.Sprintf("select id from %s", tableName),
-		DeleteSQL:         fmt.Sprintf("delete from %s", tableName),
-		UpdateSQL:         fmt.Sprintf("update %s set", tableName),
-		CreateSQL:         fmt.Sprintf("insert into %s(%s) values", tableName, strings.Join(column[1:], ",")),
+		FindSQL:           fmt.Sprintf("select %s from %s", strings.Join(qualifiedColumn, ","), tableName),
+		Columns:           strings.Join(qualifiedColumn, ","),
+		SaveSQL:           fmt.Sprintf("%s where %s", strings.Join(saveColumn, ","), saveCond),
+		SaveValue:         strings.Join(saveValue, ","),
+		CreateColumns:     strings.Join(column[1:], ","),
+		InsertColumns:     strings.Join(column, ","),
 		ColumnCount:       len(column),
 		CreatePlaceHolder: strings.Join(placeHolder[1:], ","),
 		PlaceHolder:       strings.Join(placeHolder, ","),
@@ -223,6 +481,11 @@ func gen(structName, tableName string, buf *bytes.Buffer, st *ast.StructType) *t
 		CreateValue:       strings.Join(value[1:], ","),
 		Scan:              strings.Join(scan, ","),
 		Fields:            tplFields,
+		Relations:         tplRelations,
+		SoftDelete:        softDelete,
+		Timestamps:        timestamps,
+		Version:           version,
+		Tenant:            tenant,
 		Lt:                "<",
 		Bt:                ">",
 		Tablename:         tableName,
@@ -230,6 +493,59 @@ func gen(structName, tableName string, buf *bytes.Buffer, st *ast.StructType) *t
 
 }
 
+type uowTpl struct {
+	Name   string
+	Fields []*uowField
+}
+
+type uowField struct {
+	Name    string
+	Package string
+}
+
+// genUnitOfWork collects the pkg.Repo fields of the unit of work struct
+func genUnitOfWork(structName string, st *ast.StructType) *uowTpl {
+	fields := make([]*uowField, 0, len(st.Fields.List))
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			panic(fmt.Sprintf("unit of work %s embeds a field, its repos must be named fields", structName))
+		}
+		pkgName, typName := exprType(field.Type)
+		if pkgName == "" || typName != "Repo" {
+			panic(fmt.Sprintf("unit of work field %s must be a pkg.Repo", field.Names[0].Name))
+		}
+		for _, name := range field.Names {
+			fields = append(fields, &uowField{
+				Name:    name.Name,
+				Package: pkgName,
+			})
+		}
+	}
+	return &uowTpl{
+		Name:   structName,
+		Fields: fields,
+	}
+}
+
+func findField(fields []*tplField, column string) *tplField {
+	for _, field := range fields {
+		if field.Column == column {
+			return field
+		}
+	}
+	return nil
+}
+
+func isNumeric(typ string) bool {
+	switch typ {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64":
+		return true
+	}
+	return false
+}
+
 type walker func(ast.Node) bool
 
 func (w walker) Visit(node ast.Node) ast.Visitor {
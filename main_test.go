@@ -0,0 +1,42 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"testing"
+)
+
+func TestGenUnitOfWorkEmbeddedField(t *testing.T) {
+	expr, err := parser.ParseExpr("struct {\n\ttestdata.Repo\n\tOrders order.Repo\n}")
+	if err != nil {
+		t.Fatalf("failed to parse struct,err: %#v\r\n", err)
+	}
+	defer func() {
+		want := "unit of work UnitOfWork embeds a field, its repos must be named fields"
+		if r := recover(); r != want {
+			t.Fatalf("unexpected panic,got: %#v,want: %s\r\n", r, want)
+		}
+	}()
+	genUnitOfWork("UnitOfWork", expr.(*ast.StructType))
+}
+
+func TestGenTenantRelation(t *testing.T) {
+	expr, err := parser.ParseExpr("struct {\n\tID int64 `gorm:\"id\"`\n\tNoteID int64 `gorm:\"note_id,belongs_to=Note\"`\n\tNote *tenant.Note\n}")
+	if err != nil {
+		t.Fatalf("failed to parse struct,err: %#v\r\n", err)
+	}
+	resolve := func(typ ast.Expr) (string, *tpl) {
+		return "tenant.Note", &tpl{
+			Tablename: "note",
+			Fields:    []*tplField{{Name: "ID", Type: "int64", Column: "id"}},
+			Tenant:    &tplField{Name: "TenantID", Type: "int64", Column: "tenant_id"},
+		}
+	}
+	defer func() {
+		want := "relation Note relates the tenant entity tenant.Note, Comment must be a tenant entity too"
+		if r := recover(); r != want {
+			t.Fatalf("unexpected panic,got: %#v,want: %s\r\n", r, want)
+		}
+	}()
+	gen("Comment", "comment", expr.(*ast.StructType), resolve)
+}
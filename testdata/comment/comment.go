@@ -0,0 +1,12 @@
+package comment
+
+import "github.com/wwq1988/gorm/testdata/tenant"
+
+// Comment comment
+type Comment struct {
+	ID       int64  `gorm:"id"`
+	TenantID int64  `gorm:"tenant_id,tenant"`
+	NoteID   int64  `gorm:"note_id,belongs_to=Note"`
+	Body     string `gorm:"body"`
+	Note     *tenant.Note
+}
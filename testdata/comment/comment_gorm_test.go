@@ -0,0 +1,48 @@
+package comment
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/wwq1988/gorm/testdata/fakedb"
+)
+
+func recordQueries(queries *[]*Query) RepoOption {
+	return WithInterceptors(func(ctx context.Context, query *Query, next Invoker) error {
+		*queries = append(*queries, query)
+		return next(ctx, query)
+	})
+}
+
+func TestTenantRelations(t *testing.T) {
+	db := fakedb.New().Open()
+	defer db.Close()
+	var queries []*Query
+	rp := NewRepo(db, recordQueries(&queries))
+	ctx := ContextWithTenant(context.Background(), 7)
+
+	if _, err := rp.JoinNote(ctx, BodyEq("a")); err != nil {
+		t.Fatalf("unexpected join err:%#v", err)
+	}
+	options := &options{}
+	WithNote()(options)
+	if err := options.preloads[0](rp.(*repo).tx, ctx, []*Comment{{NoteID: 1}, {NoteID: 2}}); err != nil {
+		t.Fatalf("unexpected preload err:%#v", err)
+	}
+	expected := []struct {
+		sql  string
+		args []interface{}
+	}{
+		{"select comment.id,comment.tenant_id,comment.note_id,comment.body,note.id,note.tenant_id,note.title from comment inner join note on comment.note_id = note.id and note.tenant_id = comment.tenant_id where (comment.tenant_id=? and (comment.body=?))", []interface{}{int64(7), "a"}},
+		{"select note.id,note.tenant_id,note.title from note where note.id in (?,?) and note.tenant_id=?", []interface{}{int64(1), int64(2), int64(7)}},
+	}
+	if len(queries) != len(expected) {
+		t.Fatalf("unexpected queries:%#v", queries)
+	}
+	for i, query := range queries {
+		if query.SQL != expected[i].sql || !reflect.DeepEqual(query.Args, expected[i].args) {
+			t.Fatalf("unexpected query %d:%s %#v", i, query.SQL, query.Args)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+CREATE TABLE comment (
+	id bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+	tenant_id bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT '租户',
+	note_id bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT '笔记',
+	body varchar(1000) NOT NULL DEFAULT '' COMMENT '内容',
+	PRIMARY KEY (id),
+	KEY idx_tenant_id_note_id (tenant_id, note_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
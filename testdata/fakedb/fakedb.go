@@ -0,0 +1,174 @@
+// Package fakedb is a database/sql driver without a database shared by the
+// tests of the generated repos, every statement affecting one row and every
+// query returning no rows
+package fakedb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+)
+
+// Connector Connector
+type Connector struct {
+	mu       sync.Mutex
+	prepares map[string]int
+	closes   int
+	executed []string
+	err      error
+}
+
+// New New
+func New() *Connector {
+	return &Connector{prepares: make(map[string]int)}
+}
+
+// Open returns a *sql.DB on c
+func (c *Connector) Open() *sql.DB {
+	return sql.OpenDB(c)
+}
+
+// Connect Connect
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &conn{connector: c}, nil
+}
+
+// Driver Driver
+func (c *Connector) Driver() driver.Driver {
+	return nil
+}
+
+// SetErr makes the statements run from now on fail with err
+func (c *Connector) SetErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+// Prepares returns the number of times sqlStr was prepared
+func (c *Connector) Prepares(sqlStr string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.prepares[sqlStr]
+}
+
+// Closes returns the number of prepared statements closed
+func (c *Connector) Closes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closes
+}
+
+// Executed returns the statements run in the order they ran
+func (c *Connector) Executed() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.executed...)
+}
+
+func (c *Connector) execute(sqlStr string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.executed = append(c.executed, sqlStr)
+	return c.err
+}
+
+// Result Result
+type Result int64
+
+// LastInsertId LastInsertId
+func (r Result) LastInsertId() (int64, error) {
+	return int64(r), nil
+}
+
+// RowsAffected RowsAffected
+func (r Result) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
+
+type conn struct {
+	connector *Connector
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	c.connector.mu.Lock()
+	defer c.connector.mu.Unlock()
+	c.connector.prepares[query]++
+	return &stmt{connector: c.connector, query: query}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.connector.execute(query); err != nil {
+		return nil, err
+	}
+	return Result(1), nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.connector.execute(query); err != nil {
+		return nil, err
+	}
+	return rows{}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *conn) Commit() error {
+	return nil
+}
+
+func (c *conn) Rollback() error {
+	return nil
+}
+
+type stmt struct {
+	connector *Connector
+	query     string
+}
+
+func (s *stmt) Close() error {
+	s.connector.mu.Lock()
+	defer s.connector.mu.Unlock()
+	s.connector.closes++
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.connector.execute(s.query); err != nil {
+		return nil, err
+	}
+	return Result(1), nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := s.connector.execute(s.query); err != nil {
+		return nil, err
+	}
+	return rows{}, nil
+}
+
+type rows struct{}
+
+func (rows) Columns() []string {
+	return nil
+}
+
+func (rows) Close() error {
+	return nil
+}
+
+func (rows) Next(dest []driver.Value) error {
+	return io.EOF
+}
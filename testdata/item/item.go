@@ -0,0 +1,8 @@
+package item
+
+// Item order_item
+type Item struct {
+	ID      int64  `gorm:"id"`
+	OrderID int64  `gorm:"order_id"`
+	Name    string `gorm:"name"`
+}
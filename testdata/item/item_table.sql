@@ -0,0 +1,7 @@
+CREATE TABLE order_item (
+	id bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+	order_id bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT '订单',
+	name varchar(100) NOT NULL DEFAULT '' COMMENT '名称',
+	PRIMARY KEY (id),
+	KEY idx_order_id (order_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
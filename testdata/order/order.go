@@ -0,0 +1,15 @@
+package order
+
+import (
+	"github.com/wwq1988/gorm/testdata"
+	"github.com/wwq1988/gorm/testdata/item"
+)
+
+// Order orders
+type Order struct {
+	ID     int64 `gorm:"id"`
+	UserID int64 `gorm:"user_id,belongs_to=User"`
+	Amount int64 `gorm:"amount"`
+	User   *testdata.User
+	Items  []*item.Item `gorm:"order_id,has_many"`
+}
@@ -0,0 +1,99 @@
+package order
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/wwq1988/gorm/testdata"
+	"github.com/wwq1988/gorm/testdata/fakedb"
+)
+
+func TestPreload(t *testing.T) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s",
+		"root",
+		"devilsm8875",
+		"127.0.0.1",
+		3306,
+		"testdata",
+		"parseTime=true",
+	)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("failed to open db,err: %#v\r\n", err)
+	}
+	userID, err := testdata.NewRepo(db).Create(context.Background(), &testdata.User{Name: "user1"})
+	if err != nil {
+		t.Fatalf("failed to Create user,err: %#v\r\n", err)
+	}
+	repo := NewRepo(db)
+	givenOrders := []*Order{
+		&Order{UserID: userID, Amount: 1},
+		&Order{UserID: userID, Amount: 2},
+	}
+	if err := repo.BatchCreate(context.Background(), givenOrders); err != nil {
+		t.Fatalf("failed to BatchCreate order,err: %#v\r\n", err)
+	}
+
+	gotOrders, err := repo.Find(context.Background(), UserIDEq(userID), WithUser(), WithItems())
+	if err != nil {
+		t.Fatalf("failed to Find order,err: %#v\r\n", err)
+	}
+	if len(gotOrders) != 2 {
+		t.Fatalf("Find unexpected order count")
+	}
+	for _, gotOrder := range gotOrders {
+		if gotOrder.User == nil || gotOrder.User.ID != userID {
+			t.Fatalf("Find unexpected order user")
+		}
+		if len(gotOrder.Items) != 0 {
+			t.Fatalf("Find unexpected order items")
+		}
+	}
+
+	gotOrderUsers, err := repo.JoinUser(context.Background(), testdata.NameEq("user1").And(AmountBt(1)), WithSorterBuilder(SortByID(true)))
+	if err != nil {
+		t.Fatalf("failed to JoinUser order,err: %#v\r\n", err)
+	}
+	if len(gotOrderUsers) != 1 ||
+		gotOrderUsers[0].Order.Amount != 2 ||
+		gotOrderUsers[0].User.ID != userID {
+		t.Fatalf("JoinUser unexpected order users")
+	}
+
+	if _, err := repo.Delete(context.Background(), UserIDEq(userID)); err != nil {
+		t.Fatalf("failed to Delete order,err: %#v\r\n", err)
+	}
+	if _, err := testdata.NewRepo(db).Delete(context.Background(), testdata.IDEq(userID)); err != nil {
+		t.Fatalf("failed to Delete user,err: %#v\r\n", err)
+	}
+}
+
+func TestQueryTables(t *testing.T) {
+	ctx := context.Background()
+	db := fakedb.New().Open()
+	defer db.Close()
+	var tables []string
+	rp := NewRepo(db, WithInterceptors(func(ctx context.Context, query *Query, next Invoker) error {
+		tables = append(tables, query.Operation+":"+query.Table)
+		return next(ctx, query)
+	}))
+	if _, err := rp.JoinUser(ctx, AmountBt(1)); err != nil {
+		t.Fatalf("failed to JoinUser order,err: %#v\r\n", err)
+	}
+	orders := []*Order{&Order{ID: 1, UserID: 2}}
+	if err := rp.(*repo).preloadUser(ctx, orders); err != nil {
+		t.Fatalf("failed to preload user,err: %#v\r\n", err)
+	}
+	if err := rp.(*repo).preloadItems(ctx, orders); err != nil {
+		t.Fatalf("failed to preload items,err: %#v\r\n", err)
+	}
+	want := []string{"JoinUser:orders,user", "PreloadUser:user", "PreloadItems:order_item"}
+	if !reflect.DeepEqual(tables, want) {
+		t.Fatalf("unexpected tables,got: %v", tables)
+	}
+}
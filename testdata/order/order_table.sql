@@ -0,0 +1,7 @@
+CREATE TABLE orders (
+	id bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+	user_id bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT '用户',
+	amount bigint(20) NOT NULL DEFAULT 0 COMMENT '金额',
+	PRIMARY KEY (id),
+	KEY idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,8 @@
+package tenant
+
+// Note note
+type Note struct {
+	ID       int64  `gorm:"id"`
+	TenantID int64  `gorm:"tenant_id,tenant"`
+	Title    string `gorm:"title"`
+}
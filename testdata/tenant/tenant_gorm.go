@@ -0,0 +1,3849 @@
+package tenant
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TxHandler TxHandler
+type TxHandler func(ctx context.Context, tx Tx) error
+
+// Repo Repo
+type Repo interface {
+	InReadOnlyTx(ctx context.Context, txHandler TxHandler, opts ...TxOption) error
+	InSQLTx(ctx context.Context, sqlTxHandler SQLTxHandler, opts ...TxOption) error
+	BindTx(sqlTx *sql.Tx) Tx
+	Executor() Executor
+	Tx
+}
+
+// SQLTxHandler SQLTxHandler
+type SQLTxHandler func(ctx context.Context, sqlTx *sql.Tx) error
+
+type txOptions struct {
+	sql.TxOptions
+	retryPolicy *RetryPolicy
+}
+
+// TxOption TxOption
+type TxOption func(*txOptions)
+
+// WithTxOptions WithTxOptions, a nil sqlTxOptions meaning the defaults
+func WithTxOptions(sqlTxOptions *sql.TxOptions) TxOption {
+	return func(o *txOptions) {
+		if sqlTxOptions == nil {
+			o.TxOptions = sql.TxOptions{}
+			return
+		}
+		o.TxOptions = *sqlTxOptions
+	}
+}
+
+// WithIsolation WithIsolation
+func WithIsolation(isolation sql.IsolationLevel) TxOption {
+	return func(o *txOptions) {
+		o.Isolation = isolation
+	}
+}
+
+// WithReadOnly WithReadOnly
+func WithReadOnly() TxOption {
+	return func(o *txOptions) {
+		o.ReadOnly = true
+	}
+}
+
+// WithRetry WithRetry
+func WithRetry(retryPolicy RetryPolicy) TxOption {
+	return func(o *txOptions) {
+		o.retryPolicy = &retryPolicy
+	}
+}
+
+// RetryPolicy re-runs a whole TxHandler in a fresh transaction when it
+// fails with an error Retryable reports true for, the dialect's deadlock
+// and lock wait timeout errors by default
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Retryable   func(error) bool
+}
+
+const maxBackoff = time.Duration(1<<63 - 1)
+
+// backoff returns the exponential backoff with jitter before the attempt+1th run
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.BaseBackoff
+	// doubling stops at MaxBackoff or before overflowing
+	for i := 1; i < attempt && backoff > 0 && backoff <= maxBackoff/2; i++ {
+		if p.MaxBackoff > 0 && backoff >= p.MaxBackoff {
+			break
+		}
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+// Tx Tx
+type Tx interface {
+	InTx(ctx context.Context, txHandler TxHandler, opts ...TxOption) error
+	Find(ctx context.Context, filter Filter, opts ...Option) ([]*Note, error)
+	FindOne(ctx context.Context, filter Filter, opts ...Option) (*Note, error)
+	Count(ctx context.Context, filter Filter, opts ...Option) (int64, error)
+	Delete(ctx context.Context, filter Filter) (int64, error)
+	Update(ctx context.Context, filter Filter, updaters ...Updater) (int64, error)
+	UpdateByID(ctx context.Context, id int64, updaters ...Updater) (int64, error)
+	Save(ctx context.Context, obj *Note) (int64, error)
+	Create(ctx context.Context, obj *Note) (int64, error)
+	BatchCreate(ctx context.Context, objs []*Note) error
+	DistinctID(ctx context.Context, filter Filter) ([]int64, error)
+	DistinctTenantID(ctx context.Context, filter Filter) ([]int64, error)
+	DistinctTitle(ctx context.Context, filter Filter) ([]string, error)
+	SelectID(ctx context.Context, filter Filter, opts ...Option) (Subquery, error)
+	SelectTenantID(ctx context.Context, filter Filter, opts ...Option) (Subquery, error)
+	SelectTitle(ctx context.Context, filter Filter, opts ...Option) (Subquery, error)
+}
+
+// Executor is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type sqlDB interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+type repo struct {
+	tx
+}
+
+type tx struct {
+	db       Executor
+	config   *config
+	inTx     bool
+	replicas *replicaSet
+	shard    *tableShard
+}
+
+type config struct {
+	now            func() time.Time
+	dialect        Dialect
+	interceptors   []Interceptor
+	tracer         Tracer
+	stmtCacheSize  int
+	stmtCache      *stmtCache
+	sharding       *sharding
+	tableResolver  func(ctx context.Context) string
+	tenantResolver func(ctx context.Context) (int64, bool)
+}
+
+// RepoOption RepoOption
+type RepoOption func(*config)
+
+// WithClock WithClock
+func WithClock(now func() time.Time) RepoOption {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+// WithDialect WithDialect
+func WithDialect(dialect Dialect) RepoOption {
+	return func(c *config) {
+		c.dialect = dialect
+	}
+}
+
+// WithInterceptors appends interceptors run around every statement, the
+// first one being the outermost
+func WithInterceptors(interceptors ...Interceptor) RepoOption {
+	return func(c *config) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// WithTracer starts a span around every generated method and transaction,
+// the span of each statement they run being a child of theirs
+func WithTracer(tracer Tracer) RepoOption {
+	return func(c *config) {
+		c.tracer = tracer
+		c.interceptors = append(c.interceptors, func(ctx context.Context, query *Query, next Invoker) error {
+			ctx, span := c.startNamedSpan(ctx, query.Operation+" "+query.Table, query.Operation)
+			span.SetAttribute("db.sql.table", query.Table)
+			span.SetAttribute("db.statement", query.SQL)
+			err := next(ctx, query)
+			span.SetAttribute("db.rows_affected", query.RowsAffected)
+			endSpan(span, err)
+			return err
+		})
+	}
+}
+
+// WithMetrics reports every statement to metrics
+func WithMetrics(metrics Metrics) RepoOption {
+	return WithInterceptors(func(ctx context.Context, query *Query, next Invoker) error {
+		err := next(ctx, query)
+		metrics.ObserveQuery(query.Entity, query.Operation, query.Duration, err)
+		return err
+	})
+}
+
+// WithStmtCache caches up to size prepared statements keyed on their sql,
+// transactions run them through Tx.StmtContext, the pagination being bound
+// as args and the statements with in lists or several rows not being cached
+func WithStmtCache(size int) RepoOption {
+	return func(c *config) {
+		c.stmtCacheSize = size
+	}
+}
+
+// WithTableResolver runs the statements on the structurally identical table
+// tableResolver returns for their ctx, the table of the repo being used
+// when it returns "" and the statements failing with ErrInvalidTable when
+// it is not an identifier
+func WithTableResolver(tableResolver func(ctx context.Context) string) RepoOption {
+	return func(c *config) {
+		c.tableResolver = tableResolver
+	}
+}
+
+func newConfig(executor Executor, opts []RepoOption) *config {
+	config := &config{
+		now: time.Now,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if preparer, ok := executor.(preparer); ok && config.stmtCacheSize > 0 {
+		config.stmtCache = newStmtCache(preparer, config.stmtCacheSize)
+	}
+	if config.sharding != nil && config.stmtCacheSize > 0 {
+		for _, shard := range config.sharding.shards {
+			if preparer, ok := shard.db.(preparer); ok {
+				shard.stmtCache = newStmtCache(preparer, config.stmtCacheSize)
+			}
+		}
+	}
+	return config
+}
+
+// NewRepo NewRepo
+func NewRepo(db *sql.DB, opts ...RepoOption) Repo {
+	return NewRepoFromExecutor(db, opts...)
+}
+
+// NewRepoFromExecutor returns a Repo running its statements on executor,
+// InTx nests with savepoints when executor is a *sql.Tx
+func NewRepoFromExecutor(executor Executor, opts ...RepoOption) Repo {
+	_, inTx := executor.(*sql.Tx)
+	return &repo{
+		tx{db: executor, config: newConfig(executor, opts), inTx: inTx},
+	}
+}
+
+// Replica is a read only pool picked by a weighted round-robin, a Weight
+// below 1 counting as 1
+type Replica struct {
+	DB     *sql.DB
+	Weight int
+}
+
+type replica struct {
+	db        Executor
+	stmtCache *stmtCache
+}
+
+type replicaSet struct {
+	replicas []*replica
+	next     uint64
+}
+
+func (s *replicaSet) pick() *replica {
+	next := atomic.AddUint64(&s.next, 1) - 1
+	return s.replicas[next%uint64(len(s.replicas))]
+}
+
+// NewRepoWithReplicas returns a Repo running the reads made outside of a
+// transaction on replicas, and the writes, the transactions and the reads
+// of a ForcePrimary context on primary
+func NewRepoWithReplicas(primary *sql.DB, replicas []Replica, opts ...RepoOption) Repo {
+	config := newConfig(primary, opts)
+	var readers *replicaSet
+	for _, each := range replicas {
+		if readers == nil {
+			readers = &replicaSet{}
+		}
+		replica := &replica{db: each.DB}
+		if config.stmtCacheSize > 0 {
+			replica.stmtCache = newStmtCache(each.DB, config.stmtCacheSize)
+		}
+		for i := 0; i == 0 || i < each.Weight; i++ {
+			readers.replicas = append(readers.replicas, replica)
+		}
+	}
+	return &repo{
+		tx{db: primary, config: config, replicas: readers},
+	}
+}
+
+type forcePrimaryKey struct{}
+
+// ForcePrimary returns a ctx whose reads run on the primary, so that they
+// see the writes made just before
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+// writer returns the executor and the statement cache running the writes of tx
+func (tx tx) writer() (Executor, *stmtCache) {
+	if tx.shard != nil && tx.shard.db != nil {
+		return tx.shard.db, tx.shard.stmtCache
+	}
+	return tx.db, tx.config.stmtCache
+}
+
+// reader returns the executor and the statement cache running the reads of ctx
+func (tx tx) reader(ctx context.Context) (Executor, *stmtCache) {
+	if tx.replicas == nil || tx.inTx || ctx.Value(forcePrimaryKey{}) != nil {
+		return tx.writer()
+	}
+	replica := tx.replicas.pick()
+	return replica.db, replica.stmtCache
+}
+
+// ShardStrategy maps the value of the shard key column to the index of its shard
+type ShardStrategy interface {
+	Column() string
+	Shard(key interface{}) int
+}
+
+// Shard is a table holding a part of the rows, on DB or on the db of the
+// repo when DB is nil
+type Shard struct {
+	DB    *sql.DB
+	Table string
+}
+
+// shardTx is a tx bound to a shard, named apart as the receivers of tx
+// shadow its type
+type shardTx = tx
+
+type tableShard struct {
+	table     string
+	db        Executor
+	stmtCache *stmtCache
+}
+
+type sharding struct {
+	strategy ShardStrategy
+	shards   []*tableShard
+}
+
+// ErrCrossShardQuery ErrCrossShardQuery
+var ErrCrossShardQuery = errors.New("unsupported query across shards")
+
+// ErrMissingShardKey is returned when creating or saving a row whose shard
+// key is the zero value
+var ErrMissingShardKey = errors.New("missing shard key")
+
+// WithSharding splits the table across shards by strategy, a statement runs
+// on a single shard when its filter pins the shard key with an Eq and on
+// every shard otherwise, Find merging the rows in the order of the sorters
+// built with SortBy before paginating them. The strings are merged in byte
+// order, which may differ from the collation of the table, so paginating
+// across shards by a string column is rejected. When sharding on the
+// primary key the rows are created with the key set by the caller, as the
+// auto increments of the shards would collide
+func WithSharding(strategy ShardStrategy, shards []Shard) RepoOption {
+	return func(c *config) {
+		c.sharding = &sharding{strategy: strategy}
+		for _, each := range shards {
+			shard := &tableShard{table: each.Table}
+			if each.DB != nil {
+				shard.db = each.DB
+			}
+			c.sharding.shards = append(c.sharding.shards, shard)
+		}
+	}
+}
+
+// TableShards returns n shards on the db of the repo named after the table
+// suffixed with _00, _01...
+func TableShards(n int) []Shard {
+	shards := make([]Shard, 0, n)
+	for i := 0; i < n; i++ {
+		shards = append(shards, Shard{Table: fmt.Sprintf("note_%02d", i)})
+	}
+	return shards
+}
+
+type modShardStrategy struct {
+	column string
+	shards int
+}
+
+// ModShardStrategy shards on column by the integer keys modulo shards, the
+// other keys being hashed
+func ModShardStrategy(column string, shards int) ShardStrategy {
+	return modShardStrategy{column: column, shards: shards}
+}
+
+// Column Column
+func (s modShardStrategy) Column() string {
+	return s.column
+}
+
+// Shard Shard
+func (s modShardStrategy) Shard(key interface{}) int {
+	value := reflect.ValueOf(key)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		shards := int64(s.shards)
+		return int((value.Int()%shards + shards) % shards)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(value.Uint() % uint64(s.shards))
+	}
+	hash := fnv.New32a()
+	fmt.Fprint(hash, key)
+	return int(hash.Sum32() % uint32(s.shards))
+}
+
+// from returns the table of the statements of tx, a shard being aliased as
+// the table so that the qualified columns of the filters still apply
+func (tx tx) from() string {
+	if tx.shard == nil {
+		return "note"
+	}
+	return tx.shard.table + " note"
+}
+
+// tableName returns the physical table of tx
+func (tx tx) tableName() string {
+	if tx.shard == nil {
+		return "note"
+	}
+	return tx.shard.table
+}
+
+func (tx tx) deleteFrom() string {
+	if tx.shard == nil {
+		return "delete from note"
+	}
+	return "delete note from " + tx.from()
+}
+
+// ErrMissingTenant is returned by the statements run without a tenant
+var ErrMissingTenant = errors.New("missing tenant")
+
+// ErrTenantUpdate is returned by the updates setting the tenant column
+var ErrTenantUpdate = errors.New("update of the tenant column")
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a ctx scoping the statements of the repo to tenant
+func ContextWithTenant(ctx context.Context, tenant int64) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// WithTenantResolver takes the tenant of the statements from tenantResolver
+// instead of ContextWithTenant, so that the repos of several entities can
+// share the tenant of the application ctx
+func WithTenantResolver(tenantResolver func(ctx context.Context) (int64, bool)) RepoOption {
+	return func(c *config) {
+		c.tenantResolver = tenantResolver
+	}
+}
+
+// tenant returns the tenant of ctx, ErrMissingTenant when there is none
+func (tx tx) tenant(ctx context.Context) (int64, error) {
+	var tenant int64
+	var ok bool
+	if tx.config.tenantResolver != nil {
+		tenant, ok = tx.config.tenantResolver(ctx)
+	} else {
+		tenant, ok = ctx.Value(tenantKey{}).(int64)
+	}
+	if !ok {
+		return tenant, ErrMissingTenant
+	}
+	return tenant, nil
+}
+
+// scopeTenant restricts filter to the rows of the tenant of ctx
+func (tx tx) scopeTenant(ctx context.Context, filter Filter) (Filter, error) {
+	tenant, err := tx.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scoped := TenantIDEq(tenant)
+	if filter == nil || filter.Cond() == "" {
+		return scoped, nil
+	}
+	return scoped.And(group(filter)), nil
+}
+
+// ErrInvalidTable is returned for a table given to WithTable or returned by
+// the table resolver which is not a plain or schema qualified identifier
+var ErrInvalidTable = errors.New("invalid table")
+
+var tablePattern = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*(\\.[A-Za-z_][A-Za-z0-9_]*)?$")
+
+// bindTable returns tx bound to table or to the table resolved for ctx,
+// which take precedence over the shards
+func (tx tx) bindTable(ctx context.Context, table string) (tx, error) {
+	if table == "" && tx.config.tableResolver != nil {
+		table = tx.config.tableResolver(ctx)
+	}
+	if table == "" || tx.shard != nil {
+		return tx, nil
+	}
+	if !tablePattern.MatchString(table) {
+		return tx, fmt.Errorf("%w: %q", ErrInvalidTable, table)
+	}
+	bound := tx
+	bound.shard = &tableShard{table: table}
+	return bound, nil
+}
+
+// route returns tx bound to the shards a statement filtered by filter runs on
+func (tx tx) route(filter Filter) ([]shardTx, error) {
+	sharding := tx.config.sharding
+	if sharding == nil || tx.shard != nil {
+		return []shardTx{tx}, nil
+	}
+	if pinner, ok := filter.(pinner); ok {
+		if key, ok := pinner.pinned(sharding.strategy.Column()); ok {
+			routed, err := tx.onShard(sharding.strategy.Shard(key))
+			if err != nil {
+				return nil, err
+			}
+			return []shardTx{routed}, nil
+		}
+	}
+	shards := make([]shardTx, 0, len(sharding.shards))
+	for i := range sharding.shards {
+		routed, err := tx.onShard(i)
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, routed)
+	}
+	return shards, nil
+}
+
+// shardOf returns tx bound to the shard of obj
+func (tx tx) shardOf(obj *Note) (shardTx, error) {
+	sharding := tx.config.sharding
+	if sharding == nil || tx.shard != nil {
+		return tx, nil
+	}
+	key, ok := shardKey(obj, sharding.strategy.Column())
+	if !ok {
+		return tx, fmt.Errorf("no shard key column %s", sharding.strategy.Column())
+	}
+	if reflect.ValueOf(key).IsZero() {
+		return tx, fmt.Errorf("%w: %s", ErrMissingShardKey, sharding.strategy.Column())
+	}
+	return tx.onShard(sharding.strategy.Shard(key))
+}
+
+// insertsKey reports whether the rows are sharded on the primary key, which
+// the inserts then set
+func (tx tx) insertsKey() bool {
+	return tx.config.sharding != nil && tx.config.sharding.strategy.Column() == "id"
+}
+
+func (tx tx) onShard(index int) (shardTx, error) {
+	shards := tx.config.sharding.shards
+	if index < 0 || index >= len(shards) {
+		return tx, fmt.Errorf("shard %d out of range", index)
+	}
+	shard := shards[index]
+	if shard.db != nil && tx.inTx {
+		return tx, errors.New("do not support shard on another db in tx")
+	}
+	routed := tx
+	routed.shard = shard
+	if shard.db != nil {
+		routed.replicas = nil
+	}
+	return routed, nil
+}
+
+// sum runs do on the shards filter is routed to, summing their results
+func (tx tx) sum(filter Filter, do func(shard shardTx) (int64, error)) (int64, error) {
+	shards, err := tx.route(filter)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, shard := range shards {
+		n, err := do(shard)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// shardKey returns the value of the column of obj
+func shardKey(obj *Note, column string) (interface{}, bool) {
+	switch column {
+	case "id":
+		return obj.ID, true
+	case "tenant_id":
+		return obj.TenantID, true
+	case "title":
+		return obj.Title, true
+	}
+	return nil, false
+}
+
+// compareColumn compares a and b on column, false when column is not an
+// ordered field
+func compareColumn(a, b *Note, column string) (int, bool) {
+	switch column {
+	case "id":
+		switch {
+		case a.ID < b.ID:
+			return -1, true
+		case a.ID > b.ID:
+			return 1, true
+		}
+		return 0, true
+	case "tenant_id":
+		switch {
+		case a.TenantID < b.TenantID:
+			return -1, true
+		case a.TenantID > b.TenantID:
+			return 1, true
+		}
+		return 0, true
+	case "title":
+		switch {
+		case a.Title < b.Title:
+			return -1, true
+		case a.Title > b.Title:
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// stringColumn reports whether column holds strings
+func stringColumn(column string) bool {
+	switch column {
+	case "title":
+		return true
+	}
+	return false
+}
+
+// lessBySorter returns the order of sorterBuilder merging the rows of several
+// shards, an error when paginated by a string column
+func lessBySorter(sorterBuilder SorterBuilder, paginated bool) (func(a, b *Note) bool, error) {
+	type sortKey struct {
+		column string
+		desc   bool
+	}
+	var sortKeys []sortKey
+	for _, each := range strings.Split(sorterBuilder.Build(), ",") {
+		fields := strings.Fields(each)
+		if len(fields) == 0 {
+			continue
+		}
+		column := strings.TrimPrefix(fields[0], "note.")
+		if _, ok := compareColumn(&Note{}, &Note{}, column); !ok {
+			return nil, fmt.Errorf("%w: sort by %s", ErrCrossShardQuery, fields[0])
+		}
+		if paginated && stringColumn(column) {
+			return nil, fmt.Errorf("%w: paginate by %s", ErrCrossShardQuery, fields[0])
+		}
+		sortKeys = append(sortKeys, sortKey{column: column, desc: len(fields) > 1 && strings.EqualFold(fields[1], "desc")})
+	}
+	return func(a, b *Note) bool {
+		for _, sortKey := range sortKeys {
+			cmp, _ := compareColumn(a, b, sortKey.column)
+			if sortKey.desc {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	}, nil
+}
+
+// WithTx returns a Tx bound to a transaction started elsewhere
+func WithTx(sqlTx *sql.Tx, opts ...RepoOption) Tx {
+	return &tx{db: sqlTx, config: newConfig(sqlTx, opts), inTx: true}
+}
+
+// BindTx returns a Tx sharing the options of rp bound to a transaction started elsewhere
+func (rp repo) BindTx(sqlTx *sql.Tx) Tx {
+	return &tx{db: sqlTx, config: rp.config, inTx: true}
+}
+
+// Executor returns the executor the statements of rp run on
+func (rp repo) Executor() Executor {
+	return rp.db
+}
+
+// InTx InTx
+func (rp repo) InTx(ctx context.Context, txHandler TxHandler, opts ...TxOption) error {
+	return rp.InSQLTx(ctx, func(ctx context.Context, sqlTx *sql.Tx) error {
+		return txHandler(ctx, &tx{db: sqlTx, config: rp.config, inTx: true})
+	}, opts...)
+}
+
+// InSQLTx runs sqlTxHandler like InTx but with the *sql.Tx itself, so that
+// the repos of other entities on the same pool can be bound to it with BindTx
+func (rp repo) InSQLTx(ctx context.Context, sqlTxHandler SQLTxHandler, opts ...TxOption) error {
+	if rp.inTx {
+		sqlTx, ok := rp.db.(*sql.Tx)
+		if !ok {
+			return errors.New("do not support tx")
+		}
+		return rp.tx.InTx(ctx, func(ctx context.Context, tx Tx) error {
+			return sqlTxHandler(ctx, sqlTx)
+		}, opts...)
+	}
+	ctx, span := rp.config.startSpan(ctx, "InTx")
+	err := rp.retryTx(ctx, sqlTxHandler, opts...)
+	endSpan(span, err)
+	return err
+}
+
+func (rp repo) retryTx(ctx context.Context, sqlTxHandler SQLTxHandler, opts ...TxOption) error {
+	txOptions := &txOptions{}
+	for _, opt := range opts {
+		opt(txOptions)
+	}
+	retryPolicy := txOptions.retryPolicy
+	if retryPolicy == nil {
+		return rp.runTx(ctx, sqlTxHandler, &txOptions.TxOptions)
+	}
+	retryable := retryPolicy.Retryable
+	if retryable == nil {
+		retryable = rp.config.dialect.retryable
+	}
+	for attempt := 1; ; attempt++ {
+		err := rp.runTx(ctx, sqlTxHandler, &txOptions.TxOptions)
+		if err == nil || attempt >= retryPolicy.MaxAttempts || !retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(retryPolicy.backoff(attempt)):
+		}
+	}
+}
+
+func (rp repo) runTx(ctx context.Context, sqlTxHandler SQLTxHandler, txOptions *sql.TxOptions) error {
+	db, ok := rp.db.(sqlDB)
+	if !ok {
+		return errors.New("do not support tx")
+	}
+	dbTx, err := db.BeginTx(ctx, txOptions)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+	if err := sqlTxHandler(ctx, dbTx); err != nil {
+		return err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+var savepointSeq uint64
+
+// InTx InTx
+func (tx tx) InTx(ctx context.Context, txHandler TxHandler, opts ...TxOption) error {
+	if !tx.inTx {
+		return errors.New("do not support tx")
+	}
+	if len(opts) != 0 {
+		return errors.New("do not support tx options in nested tx")
+	}
+	ctx, span := tx.config.startSpan(ctx, "InTx")
+	err := tx.inSavepoint(ctx, txHandler)
+	endSpan(span, err)
+	return err
+}
+
+func (tx tx) inSavepoint(ctx context.Context, txHandler TxHandler) error {
+	savepoint := fmt.Sprintf("sp_%d", atomic.AddUint64(&savepointSeq, 1))
+	if err := tx.savepoint(ctx, "Savepoint", "savepoint "+savepoint); err != nil {
+		return err
+	}
+	if err := txHandler(ctx, tx); err != nil {
+		if rollbackErr := tx.savepoint(ctx, "RollbackToSavepoint", "rollback to savepoint "+savepoint); rollbackErr != nil {
+			return rollbackErr
+		}
+		return err
+	}
+	if err := tx.savepoint(ctx, "ReleaseSavepoint", "release savepoint "+savepoint); err != nil {
+		return err
+	}
+	return nil
+}
+
+// InReadOnlyTx InReadOnlyTx
+func (rp repo) InReadOnlyTx(ctx context.Context, txHandler TxHandler, opts ...TxOption) error {
+	return rp.InTx(ctx, txHandler, append(opts, WithReadOnly())...)
+}
+
+// Redacted is a bind arg logged as [REDACTED], the generated filters,
+// updaters and inserts wrap the args of sensitive columns with it
+type Redacted struct {
+	value interface{}
+}
+
+// Redact Redact
+func Redact(value interface{}) Redacted {
+	if redacted, ok := value.(Redacted); ok {
+		return redacted
+	}
+	return Redacted{value: value}
+}
+
+func redactAll(args []interface{}) []interface{} {
+	redacted := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		redacted = append(redacted, Redact(arg))
+	}
+	return redacted
+}
+
+// Value Value
+func (r Redacted) Value() (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(r.value)
+}
+
+// String String
+func (r Redacted) String() string {
+	return "[REDACTED]"
+}
+
+// GoString GoString
+func (r Redacted) GoString() string {
+	return r.String()
+}
+
+// Logger is satisfied by *slog.Logger
+type Logger interface {
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+type logConfig struct {
+	slowThreshold time.Duration
+}
+
+// LogOption LogOption
+type LogOption func(*logConfig)
+
+// WithSlowThreshold logs the statements running for slowThreshold or longer as warnings
+func WithSlowThreshold(slowThreshold time.Duration) LogOption {
+	return func(c *logConfig) {
+		c.slowThreshold = slowThreshold
+	}
+}
+
+// WithLogger logs every statement to logger with its placeholders and
+// args, the args of sensitive columns being redacted
+func WithLogger(logger Logger, opts ...LogOption) RepoOption {
+	logConfig := &logConfig{}
+	for _, opt := range opts {
+		opt(logConfig)
+	}
+	return WithInterceptors(func(ctx context.Context, query *Query, next Invoker) error {
+		err := next(ctx, query)
+		args := make([]interface{}, 0, len(query.Args))
+		for _, arg := range query.Args {
+			if redacted, ok := arg.(Redacted); ok {
+				arg = redacted.String()
+			}
+			args = append(args, arg)
+		}
+		attrs := []interface{}{
+			"operation", query.Operation,
+			"entity", query.Entity,
+			"table", query.Table,
+			"sql", query.SQL,
+			"args", args,
+			"duration", query.Duration,
+			"rows", query.RowsAffected,
+		}
+		switch {
+		case err != nil:
+			logger.ErrorContext(ctx, "query failed", append(attrs, "error", err)...)
+		case logConfig.slowThreshold > 0 && query.Duration >= logConfig.slowThreshold:
+			logger.WarnContext(ctx, "slow query", attrs...)
+		default:
+			logger.InfoContext(ctx, "query", attrs...)
+		}
+		return err
+	})
+}
+
+// Tracer starts the spans of the repo, it is meant to be backed by an
+// OpenTelemetry tracer or by a SpanRecorder in tests
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span Span
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+
+func (noopSpan) RecordError(err error) {}
+
+func (noopSpan) End() {}
+
+// startSpan starts the span of the generated method operation
+func (c *config) startSpan(ctx context.Context, operation string) (context.Context, Span) {
+	return c.startNamedSpan(ctx, "Note."+operation, operation)
+}
+
+// startNamedSpan starts the span name of operation with the db attributes of the repo
+func (c *config) startNamedSpan(ctx context.Context, name string, operation string) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := c.tracer.Start(ctx, name)
+	span.SetAttribute("db.system", c.dialect.system())
+	span.SetAttribute("db.operation", operation)
+	return ctx, span
+}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// SpanRecorder is an in-memory Tracer keeping the ended spans
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan RecordedSpan
+type RecordedSpan struct {
+	Name       string
+	Parent     *RecordedSpan
+	Attributes map[string]interface{}
+	Err        error
+	recorder   *SpanRecorder
+}
+
+type recordedSpanKey struct{}
+
+// Start Start
+func (r *SpanRecorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &RecordedSpan{
+		Name:       name,
+		Attributes: make(map[string]interface{}),
+		recorder:   r,
+	}
+	span.Parent, _ = ctx.Value(recordedSpanKey{}).(*RecordedSpan)
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+// Spans returns the ended spans in the order they ended
+func (r *SpanRecorder) Spans() []*RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*RecordedSpan(nil), r.spans...)
+}
+
+// SetAttribute SetAttribute
+func (s *RecordedSpan) SetAttribute(key string, value interface{}) {
+	s.Attributes[key] = value
+}
+
+// RecordError RecordError
+func (s *RecordedSpan) RecordError(err error) {
+	s.Err = err
+}
+
+// End End
+func (s *RecordedSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans = append(s.recorder.spans, s)
+}
+
+// Metrics receives the outcome of every statement
+type Metrics interface {
+	ObserveQuery(entity, operation string, duration time.Duration, err error)
+}
+
+// DefaultBuckets are the latency buckets in seconds used by NewPrometheusMetrics
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics counts the statements, their errors and their latency
+// per entity and operation and exposes them in the Prometheus text format,
+// it may be shared by the repos of several entities
+type PrometheusMetrics struct {
+	mu      sync.Mutex
+	buckets []float64
+	series  map[metricsKey]*metricsSeries
+}
+
+type metricsKey struct {
+	entity    string
+	operation string
+}
+
+type metricsSeries struct {
+	count   uint64
+	errors  uint64
+	sum     float64
+	buckets []uint64
+}
+
+// NewPrometheusMetrics returns a PrometheusMetrics with the latency buckets
+// in seconds, DefaultBuckets when none are given
+func NewPrometheusMetrics(buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &PrometheusMetrics{
+		buckets: buckets,
+		series:  make(map[metricsKey]*metricsSeries),
+	}
+}
+
+// ObserveQuery ObserveQuery
+func (m *PrometheusMetrics) ObserveQuery(entity, operation string, duration time.Duration, err error) {
+	seconds := duration.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := metricsKey{entity: entity, operation: operation}
+	series, ok := m.series[key]
+	if !ok {
+		series = &metricsSeries{buckets: make([]uint64, len(m.buckets))}
+		m.series[key] = series
+	}
+	series.count++
+	series.sum += seconds
+	if err != nil {
+		series.errors++
+	}
+	for i, bucket := range m.buckets {
+		if bucket >= seconds {
+			series.buckets[i]++
+		}
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text format
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	keys := make([]metricsKey, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].entity != keys[j].entity {
+			return keys[i].entity < keys[j].entity
+		}
+		return keys[i].operation < keys[j].operation
+	})
+	var buf strings.Builder
+	buf.WriteString("# HELP gorm_queries_total Number of statements run.\n# TYPE gorm_queries_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "gorm_queries_total{entity=%q,operation=%q} %d\n", key.entity, key.operation, m.series[key].count)
+	}
+	buf.WriteString("# HELP gorm_query_errors_total Number of statements that failed.\n# TYPE gorm_query_errors_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "gorm_query_errors_total{entity=%q,operation=%q} %d\n", key.entity, key.operation, m.series[key].errors)
+	}
+	buf.WriteString("# HELP gorm_query_duration_seconds Latency of the statements.\n# TYPE gorm_query_duration_seconds histogram\n")
+	for _, key := range keys {
+		series := m.series[key]
+		for i, bucket := range m.buckets {
+			fmt.Fprintf(&buf, "gorm_query_duration_seconds_bucket{entity=%q,operation=%q,le=\"%g\"} %d\n", key.entity, key.operation, bucket, series.buckets[i])
+		}
+		fmt.Fprintf(&buf, "gorm_query_duration_seconds_bucket{entity=%q,operation=%q,le=\"+Inf\"} %d\n", key.entity, key.operation, series.count)
+		fmt.Fprintf(&buf, "gorm_query_duration_seconds_sum{entity=%q,operation=%q} %g\n", key.entity, key.operation, series.sum)
+		fmt.Fprintf(&buf, "gorm_query_duration_seconds_count{entity=%q,operation=%q} %d\n", key.entity, key.operation, series.count)
+	}
+	m.mu.Unlock()
+	n, err := io.WriteString(w, buf.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics to a Prometheus scrape
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+type preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// stmtCache is a LRU of the statements prepared on preparer, the evicted
+// statements being closed once no more in use
+type stmtCache struct {
+	mu       sync.Mutex
+	preparer preparer
+	size     int
+	entries  map[string]*list.Element
+	lru      *list.List
+}
+
+type stmtEntry struct {
+	sqlStr  string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(preparer preparer, size int) *stmtCache {
+	return &stmtCache{
+		preparer: preparer,
+		size:     size,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// acquire returns the statement of sqlStr, preparing it on a miss
+func (c *stmtCache) acquire(ctx context.Context, sqlStr string) (*stmtEntry, error) {
+	if entry := c.lookup(sqlStr); entry != nil {
+		return entry, nil
+	}
+	stmt, err := c.preparer.PrepareContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[sqlStr]; ok {
+		stmt.Close()
+		return c.use(elem), nil
+	}
+	entry := &stmtEntry{sqlStr: sqlStr, stmt: stmt, refs: 1}
+	c.entries[sqlStr] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		evicted := c.lru.Remove(c.lru.Back()).(*stmtEntry)
+		delete(c.entries, evicted.sqlStr)
+		evicted.evicted = true
+		if evicted.refs == 0 {
+			evicted.stmt.Close()
+		}
+	}
+	return entry, nil
+}
+
+func (c *stmtCache) lookup(sqlStr string) *stmtEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[sqlStr]
+	if !ok {
+		return nil
+	}
+	return c.use(elem)
+}
+
+func (c *stmtCache) use(elem *list.Element) *stmtEntry {
+	c.lru.MoveToFront(elem)
+	entry := elem.Value.(*stmtEntry)
+	entry.refs++
+	return entry
+}
+
+func (c *stmtCache) release(entry *stmtEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.refs--
+	if entry.evicted && entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// cacheable reports whether the shape of sqlStr does not depend on its args,
+// the generated in lists and multi-row inserts having one shape per length
+// which would evict the hot statements
+func cacheable(sqlStr string) bool {
+	return !strings.Contains(sqlStr, " in (?") && !strings.Contains(sqlStr, "),(")
+}
+
+// prepared returns the statement of sqlStr from cache bound to db and the
+// func releasing it, a nil statement when there is no cache or sqlStr is
+// not cacheable
+func prepared(ctx context.Context, db Executor, cache *stmtCache, sqlStr string) (*sql.Stmt, func(), error) {
+	if cache == nil || !cacheable(sqlStr) {
+		return nil, func() {}, nil
+	}
+	entry, err := cache.acquire(ctx, sqlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() {
+		cache.release(entry)
+	}
+	if sqlTx, ok := db.(*sql.Tx); ok && cache.preparer != preparer(sqlTx) {
+		// the statement bound to the transaction is a new one on every call
+		txStmt := sqlTx.StmtContext(ctx, entry.stmt)
+		return txStmt, func() {
+			txStmt.Close()
+			release()
+		}, nil
+	}
+	return entry.stmt, release, nil
+}
+
+// Query describes a statement passed through the interceptors, Table
+// listing the joined tables after the table of the repo, Duration
+// and RowsAffected being filled once it has run, RowsAffected counting the
+// scanned rows for queries
+type Query struct {
+	Operation    string
+	Entity       string
+	Table        string
+	SQL          string
+	Args         []interface{}
+	Duration     time.Duration
+	RowsAffected int64
+}
+
+// Invoker runs query
+type Invoker func(ctx context.Context, query *Query) error
+
+// Interceptor wraps the statements of the repo, calling next to run query
+type Interceptor func(ctx context.Context, query *Query, next Invoker) error
+
+// invoke runs do through the interceptors of tx
+func (tx tx) invoke(ctx context.Context, query *Query, do Invoker) error {
+	invoker := func(ctx context.Context, query *Query) error {
+		start := time.Now()
+		err := do(ctx, query)
+		query.Duration = time.Since(start)
+		return err
+	}
+	for i := len(tx.config.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := tx.config.interceptors[i], invoker
+		invoker = func(ctx context.Context, query *Query) error {
+			return interceptor(ctx, query, next)
+		}
+	}
+	return invoker(ctx, query)
+}
+
+// exec runs an ExecContext through the interceptors of tx
+func (tx tx) exec(ctx context.Context, operation string, sqlStr string, args ...interface{}) (sql.Result, error) {
+	query := &Query{Operation: operation, Entity: "Note", Table: tx.tableName(), SQL: sqlStr, Args: args}
+	var result sql.Result
+	err := tx.invoke(ctx, query, func(ctx context.Context, query *Query) error {
+		db, stmtCache := tx.writer()
+		stmt, release, err := prepared(ctx, db, stmtCache, query.SQL)
+		if err != nil {
+			return err
+		}
+		defer release()
+		if stmt != nil {
+			result, err = stmt.ExecContext(ctx, query.Args...)
+		} else {
+			result, err = db.ExecContext(ctx, query.SQL, query.Args...)
+		}
+		if err != nil {
+			return err
+		}
+		query.RowsAffected, err = result.RowsAffected()
+		return err
+	})
+	return result, err
+}
+
+// savepoint runs a savepoint statement through the interceptors of tx,
+// bypassing the statement cache as savepoint names are unique
+func (tx tx) savepoint(ctx context.Context, operation string, sqlStr string) error {
+	query := &Query{Operation: operation, Entity: "Note", Table: tx.tableName(), SQL: sqlStr}
+	return tx.invoke(ctx, query, func(ctx context.Context, query *Query) error {
+		_, err := tx.db.ExecContext(ctx, query.SQL)
+		return err
+	})
+}
+
+// query runs a QueryContext through the interceptors of tx, calling scan for each row
+func (tx tx) query(ctx context.Context, operation string, sqlStr string, scan func(rows *sql.Rows) error, args ...interface{}) error {
+	return tx.queryTable(ctx, operation, tx.tableName(), sqlStr, scan, args...)
+}
+
+// queryTable is query for the statements reading table rather than the table of tx
+func (tx tx) queryTable(ctx context.Context, operation string, table string, sqlStr string, scan func(rows *sql.Rows) error, args ...interface{}) error {
+	query := &Query{Operation: operation, Entity: "Note", Table: table, SQL: sqlStr, Args: args}
+	return tx.invoke(ctx, query, func(ctx context.Context, query *Query) error {
+		db, stmtCache := tx.reader(ctx)
+		stmt, release, err := prepared(ctx, db, stmtCache, query.SQL)
+		if err != nil {
+			return err
+		}
+		defer release()
+		var rows *sql.Rows
+		if stmt != nil {
+			rows, err = stmt.QueryContext(ctx, query.Args...)
+		} else {
+			rows, err = db.QueryContext(ctx, query.SQL, query.Args...)
+		}
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			if err := scan(rows); err != nil {
+				return err
+			}
+			query.RowsAffected++
+		}
+		return rows.Err()
+	})
+}
+
+// Find Find
+func (tx tx) Find(ctx context.Context, filter Filter, opts ...Option) (results []*Note, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Find")
+	defer func() {
+		endSpan(span, err)
+	}()
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	filter, err = tx.scopeTenant(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	tx, err = tx.bindTable(ctx, options.table)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(shards) == 1 {
+		results, err = shards[0].find(ctx, "Find", filter, options)
+	} else {
+		results, err = tx.findShards(ctx, "Find", shards, filter, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, preload := range options.preloads {
+		if err := preload(tx, ctx, results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (tx tx) find(ctx context.Context, operation string, filter Filter, options *options) ([]*Note, error) {
+	findSQL := "select note.id,note.tenant_id,note.title from " + tx.from()
+	sortStr := ""
+	if options.sorterBuilder != nil {
+		sortStr = fmt.Sprintf(" order by %s ", options.sorterBuilder.Build())
+	}
+
+	paginate := ""
+	if options.paginate != nil {
+		paginate = fmt.Sprintf(" inner join (select id from %s %s limit ?, ?) tmp on note.id = tmp.id ", tx.from(), sortStr)
+		if filter != nil && filter.Cond() != "" {
+			paginate = fmt.Sprintf(" inner join (select id from %s where %s %s limit ?, ?) tmp on note.id = tmp.id ", tx.from(), filter.Cond(), sortStr)
+		}
+	}
+
+	withLock, err := tx.lockClause(options.lockMode)
+	if err != nil {
+		return nil, err
+	}
+
+	var sqlStr string
+	var args []interface{}
+	if filter == nil || filter.Cond() == "" {
+		sqlStr = fmt.Sprintf(findSQL+"%s%s%s", sortStr, paginate, withLock)
+		if paginate != "" {
+			sqlStr = fmt.Sprintf(findSQL+"%s%s", paginate, withLock)
+		}
+	} else {
+		sqlStr = fmt.Sprintf(findSQL+" where %s%s%s%s", filter.Cond(), sortStr, paginate, withLock)
+		if paginate != "" {
+			sqlStr = fmt.Sprintf(findSQL+" %s%s", paginate, withLock)
+		}
+		args = filter.Args()
+	}
+	if paginate != "" {
+		args = append(append([]interface{}{}, args...), options.paginate.offset, options.paginate.size)
+	}
+	var results []*Note
+	err = tx.query(ctx, operation, sqlStr, func(rows *sql.Rows) error {
+		result := &Note{}
+		if err := rows.Scan(&result.ID, &result.TenantID, &result.Title); err != nil {
+			return err
+		}
+		results = append(results, result)
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// findShards runs find on every shard, merging the rows in the order of the
+// sorter before paginating them
+func (tx tx) findShards(ctx context.Context, operation string, shards []shardTx, filter Filter, options *options) ([]*Note, error) {
+	var less func(a, b *Note) bool
+	if options.sorterBuilder != nil {
+		var err error
+		if less, err = lessBySorter(options.sorterBuilder, options.paginate != nil); err != nil {
+			return nil, err
+		}
+	}
+	shardOptions := *options
+	if options.paginate != nil {
+		shardOptions.paginate = &paginate{size: int(options.paginate.offset) + options.paginate.size}
+	}
+	var results []*Note
+	for _, shard := range shards {
+		shardResults, err := shard.find(ctx, operation, filter, &shardOptions)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, shardResults...)
+	}
+	if less != nil {
+		sort.SliceStable(results, func(i, j int) bool {
+			return less(results[i], results[j])
+		})
+	}
+	if options.paginate != nil {
+		start := int(options.paginate.offset)
+		if start > len(results) {
+			start = len(results)
+		}
+		end := start + options.paginate.size
+		if end > len(results) {
+			end = len(results)
+		}
+		results = results[start:end]
+	}
+	return results, nil
+}
+
+// FindOne FindOne
+func (tx tx) FindOne(ctx context.Context, filter Filter, opts ...Option) (result *Note, err error) {
+	ctx, span := tx.config.startSpan(ctx, "FindOne")
+	defer func() {
+		endSpan(span, err)
+	}()
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	filter, err = tx.scopeTenant(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	tx, err = tx.bindTable(ctx, options.table)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(shards) == 1 {
+		result, err = shards[0].findOne(ctx, filter, options)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		findOptions := *options
+		findOptions.paginate = &paginate{size: 1}
+		if options.paginate != nil {
+			findOptions.paginate.offset = options.paginate.offset
+		}
+		results, err := tx.findShards(ctx, "FindOne", shards, filter, &findOptions)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			return nil, sql.ErrNoRows
+		}
+		result = results[0]
+	}
+	for _, preload := range options.preloads {
+		if err := preload(tx, ctx, []*Note{result}); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (tx tx) findOne(ctx context.Context, filter Filter, options *options) (*Note, error) {
+	findSQL := "select note.id,note.tenant_id,note.title from " + tx.from()
+
+	sortStr := ""
+	if options.sorterBuilder != nil {
+		sortStr = fmt.Sprintf(" order by %s ", options.sorterBuilder.Build())
+	}
+
+	paginate := ""
+	if options.paginate != nil {
+		paginate = " limit ?, ? "
+	}
+
+	withLock, err := tx.lockClause(options.lockMode)
+	if err != nil {
+		return nil, err
+	}
+
+	var sqlStr string
+	var args []interface{}
+	if filter == nil || filter.Cond() == "" {
+		sqlStr = fmt.Sprintf(findSQL+"%s%s%s", sortStr, paginate, withLock)
+	} else {
+		sqlStr = fmt.Sprintf(findSQL+" where %s%s%s%s", filter.Cond(), sortStr, paginate, withLock)
+		args = filter.Args()
+	}
+	if paginate != "" {
+		args = append(append([]interface{}{}, args...), options.paginate.offset, options.paginate.size)
+	}
+	var result *Note
+	err = tx.query(ctx, "FindOne", sqlStr, func(rows *sql.Rows) error {
+		if result != nil {
+			return nil
+		}
+		result = &Note{}
+		return rows.Scan(&result.ID, &result.TenantID, &result.Title)
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, sql.ErrNoRows
+	}
+	return result, nil
+}
+
+// Count Count
+func (tx tx) Count(ctx context.Context, filter Filter, opts ...Option) (count int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Count")
+	defer func() {
+		endSpan(span, err)
+	}()
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	filter, err = tx.scopeTenant(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	tx, err = tx.bindTable(ctx, options.table)
+	if err != nil {
+		return 0, err
+	}
+	return tx.sum(filter, func(shard shardTx) (int64, error) {
+		return shard.count(ctx, filter)
+	})
+}
+
+func (tx tx) count(ctx context.Context, filter Filter) (int64, error) {
+	sqlStr := "select count(*) from " + tx.from()
+	var args []interface{}
+	if filter != nil && filter.Cond() != "" {
+		sqlStr = fmt.Sprintf("select count(*) from %s where %s", tx.from(), filter.Cond())
+		args = filter.Args()
+	}
+	var count int64
+	err := tx.query(ctx, "Count", sqlStr, func(rows *sql.Rows) error {
+		return rows.Scan(&count)
+	}, args...)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Delete Delete
+func (tx tx) Delete(ctx context.Context, filter Filter) (rowsAffected int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Delete")
+	defer func() {
+		endSpan(span, err)
+	}()
+	filter, err = tx.scopeTenant(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	return tx.sum(filter, func(shard shardTx) (int64, error) {
+		return shard.deleteRows(ctx, filter)
+	})
+}
+
+func (tx tx) deleteRows(ctx context.Context, filter Filter) (int64, error) {
+	var result sql.Result
+	var err error
+	if filter == nil || filter.Cond() == "" {
+		result, err = tx.exec(ctx, "Delete", tx.deleteFrom())
+	} else {
+		sqlStr := fmt.Sprintf("%s where %s", tx.deleteFrom(), filter.Cond())
+		result, err = tx.exec(ctx, "Delete", sqlStr, filter.Args()...)
+	}
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// Update Update
+func (tx tx) Update(ctx context.Context, filter Filter, updaters ...Updater) (rowsAffected int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Update")
+	defer func() {
+		endSpan(span, err)
+	}()
+	return tx.update(ctx, "Update", filter, updaters...)
+}
+
+func (tx tx) update(ctx context.Context, operation string, filter Filter, updaters ...Updater) (int64, error) {
+	if len(updaters) == 0 {
+		return 0, errors.New("no updaters")
+	}
+	if hasUpdater(updaters, ColumnTenantID) {
+		return 0, ErrTenantUpdate
+	}
+	tx, err := tx.bindTable(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	filter, err = tx.scopeTenant(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return tx.sum(filter, func(shard shardTx) (int64, error) {
+		return shard.updateRows(ctx, operation, filter, updaters)
+	})
+}
+
+func (tx tx) updateRows(ctx context.Context, operation string, filter Filter, updaters []Updater) (int64, error) {
+	var result sql.Result
+	var err error
+	updateStrs := make([]string, 0, len(updaters))
+	updateArgs := make([]interface{}, 0, len(updaters))
+	for _, updater := range updaters {
+		updateStrs = append(updateStrs, updater.Set())
+		updateArgs = append(updateArgs, updater.Args()...)
+	}
+	if filter == nil || filter.Cond() == "" {
+		sqlStr := fmt.Sprintf("update %s set %s", tx.from(), strings.Join(updateStrs, ","))
+		result, err = tx.exec(ctx, operation, sqlStr, updateArgs...)
+	} else {
+		sqlStr := fmt.Sprintf("update %s set %s where %s", tx.from(), strings.Join(updateStrs, ","), filter.Cond())
+		sqlArgs := append(updateArgs, filter.Args()...)
+		result, err = tx.exec(ctx, operation, sqlStr, sqlArgs...)
+	}
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// UpdateByID UpdateByID
+func (tx tx) UpdateByID(ctx context.Context, id int64, updaters ...Updater) (rowsAffected int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "UpdateByID")
+	defer func() {
+		endSpan(span, err)
+	}()
+	return tx.update(ctx, "UpdateByID", IDEq(id), updaters...)
+}
+
+// Save Save
+func (tx tx) Save(ctx context.Context, obj *Note) (rowsAffected int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Save")
+	defer func() {
+		endSpan(span, err)
+	}()
+	tenant, err := tx.tenant(ctx)
+	if err != nil {
+		return 0, err
+	}
+	obj.TenantID = tenant
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	shard, err := tx.shardOf(obj)
+	if err != nil {
+		return 0, err
+	}
+	result, err := shard.exec(ctx, "Save", "update "+shard.from()+" set tenant_id=?,title=? where id=? and tenant_id=?", obj.TenantID, obj.Title, obj.ID, obj.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err = result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// setTimestamps fills the auto managed timestamps of obj before it is created
+func (tx tx) setTimestamps(obj *Note) {
+}
+
+// Create Create
+func (tx tx) Create(ctx context.Context, obj *Note) (id int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Create")
+	defer func() {
+		endSpan(span, err)
+	}()
+	tx.setTimestamps(obj)
+	tenant, err := tx.tenant(ctx)
+	if err != nil {
+		return 0, err
+	}
+	obj.TenantID = tenant
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	shard, err := tx.shardOf(obj)
+	if err != nil {
+		return 0, err
+	}
+	if tx.insertsKey() {
+		if _, err := shard.exec(ctx, "Create", "insert into "+shard.tableName()+"(id,tenant_id,title) values (?,?,?)", obj.ID, obj.TenantID, obj.Title); err != nil {
+			return 0, err
+		}
+		return int64(obj.ID), nil
+	}
+	result, err := shard.exec(ctx, "Create", "insert into "+shard.tableName()+"(tenant_id,title) values (?,?)", obj.TenantID, obj.Title)
+	if err != nil {
+		return 0, err
+	}
+	lastInsertID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return lastInsertID, nil
+}
+
+// BatchCreate BatchCreate
+func (tx tx) BatchCreate(ctx context.Context, objs []*Note) (err error) {
+	ctx, span := tx.config.startSpan(ctx, "BatchCreate")
+	defer func() {
+		endSpan(span, err)
+	}()
+	tenant, err := tx.tenant(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return err
+	}
+	var shards []shardTx
+	objsByShard := make(map[*tableShard][]*Note)
+	for _, obj := range objs {
+		obj.TenantID = tenant
+		shard, err := tx.shardOf(obj)
+		if err != nil {
+			return err
+		}
+		if _, ok := objsByShard[shard.shard]; !ok {
+			shards = append(shards, shard)
+		}
+		objsByShard[shard.shard] = append(objsByShard[shard.shard], obj)
+	}
+	for _, shard := range shards {
+		if err := shard.batchCreate(ctx, objsByShard[shard.shard]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx tx) batchCreate(ctx context.Context, objs []*Note) error {
+	insertsKey := tx.insertsKey()
+	sqlBaseStr := "insert into " + tx.tableName() + "(tenant_id,title) values %s"
+	if insertsKey {
+		sqlBaseStr = "insert into " + tx.tableName() + "(id,tenant_id,title) values %s"
+	}
+	sqlPlaceHolder := make([]string, 0, len(objs))
+	sqlArgs := make([]interface{}, 0, len(objs)*3)
+	for _, obj := range objs {
+		tx.setTimestamps(obj)
+		if insertsKey {
+			sqlPlaceHolder = append(sqlPlaceHolder, "(?,?,?)")
+			sqlArgs = append(sqlArgs, obj.ID, obj.TenantID, obj.Title)
+			continue
+		}
+		sqlPlaceHolder = append(sqlPlaceHolder, "(?,?)")
+		sqlArgs = append(sqlArgs, obj.TenantID, obj.Title)
+	}
+	sqlStr := fmt.Sprintf(sqlBaseStr, strings.Join(sqlPlaceHolder, ","))
+	if _, err := tx.exec(ctx, "BatchCreate", sqlStr, sqlArgs...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DistinctID DistinctID
+func (tx tx) DistinctID(ctx context.Context, filter Filter) (results []int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "DistinctID")
+	defer func() {
+		endSpan(span, err)
+	}()
+	filter = (&options{}).scope(filter)
+	filter, err = tx.scopeTenant(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, shard := range shards {
+		sqlStr := "select distinct id from " + shard.from()
+		var args []interface{}
+		if filter != nil && filter.Cond() != "" {
+			sqlStr = fmt.Sprintf("select distinct id from %s where %s", shard.from(), filter.Cond())
+			args = filter.Args()
+		}
+		err := shard.query(ctx, "DistinctID", sqlStr, func(rows *sql.Rows) error {
+			var result int64
+			if err := rows.Scan(&result); err != nil {
+				return err
+			}
+			if len(shards) > 1 {
+				key := fmt.Sprintf("%#v", result)
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+			results = append(results, result)
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// DistinctTenantID DistinctTenantID
+func (tx tx) DistinctTenantID(ctx context.Context, filter Filter) (results []int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "DistinctTenantID")
+	defer func() {
+		endSpan(span, err)
+	}()
+	filter = (&options{}).scope(filter)
+	filter, err = tx.scopeTenant(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, shard := range shards {
+		sqlStr := "select distinct tenant_id from " + shard.from()
+		var args []interface{}
+		if filter != nil && filter.Cond() != "" {
+			sqlStr = fmt.Sprintf("select distinct tenant_id from %s where %s", shard.from(), filter.Cond())
+			args = filter.Args()
+		}
+		err := shard.query(ctx, "DistinctTenantID", sqlStr, func(rows *sql.Rows) error {
+			var result int64
+			if err := rows.Scan(&result); err != nil {
+				return err
+			}
+			if len(shards) > 1 {
+				key := fmt.Sprintf("%#v", result)
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+			results = append(results, result)
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// DistinctTitle DistinctTitle
+func (tx tx) DistinctTitle(ctx context.Context, filter Filter) (results []string, err error) {
+	ctx, span := tx.config.startSpan(ctx, "DistinctTitle")
+	defer func() {
+		endSpan(span, err)
+	}()
+	filter = (&options{}).scope(filter)
+	filter, err = tx.scopeTenant(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, shard := range shards {
+		sqlStr := "select distinct title from " + shard.from()
+		var args []interface{}
+		if filter != nil && filter.Cond() != "" {
+			sqlStr = fmt.Sprintf("select distinct title from %s where %s", shard.from(), filter.Cond())
+			args = filter.Args()
+		}
+		err := shard.query(ctx, "DistinctTitle", sqlStr, func(rows *sql.Rows) error {
+			var result string
+			if err := rows.Scan(&result); err != nil {
+				return err
+			}
+			if len(shards) > 1 {
+				key := fmt.Sprintf("%#v", result)
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+			results = append(results, result)
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// ErrStaleObject ErrStaleObject
+var ErrStaleObject = errors.New("stale object")
+
+// Updater Updater
+type Updater interface {
+	Set() string
+	Args() []interface{}
+}
+
+type updater struct {
+	set  string
+	args []interface{}
+}
+
+func (u *updater) Set() string {
+	return u.set
+}
+
+func (u *updater) Args() []interface{} {
+	return u.args
+}
+
+// hasUpdater reports whether one of the comma separated assignments of
+// updaters sets column, whatever its whitespace, quoting, case or table
+func hasUpdater(updaters []Updater, column Column) bool {
+	for _, updater := range updaters {
+		set := strings.Join(strings.Fields(updater.Set()), "")
+		for _, assignment := range strings.Split(set, ",") {
+			i := strings.Index(assignment, "=")
+			if i < 0 {
+				continue
+			}
+			target := assignment[:i]
+			target = target[strings.LastIndex(target, ".")+1:]
+			if strings.EqualFold(strings.Trim(target, "\x60"), string(column)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RawUpdater RawUpdater
+func RawUpdater(set string, args ...interface{}) Updater {
+	return &updater{
+		set:  set,
+		args: args,
+	}
+}
+
+// Column Column
+type Column string
+
+// Filter Filter
+type Filter interface {
+	Cond() string
+	Args() []interface{}
+}
+
+// JoinableFilter JoinableFilter
+type JoinableFilter interface {
+	Filter
+	Or(...Filter) JoinableFilter
+	And(...Filter) JoinableFilter
+}
+
+type filter struct {
+	cond string
+	args []interface{}
+	and  []Filter
+}
+
+// pinner is implemented by the filters which may pin a column to a single value
+type pinner interface {
+	pinned(column string) (interface{}, bool)
+}
+
+// group parenthesizes the cond of f, so that its ors stay inside the
+// conjunctions it is added to, keeping the keys f pins
+func group(f Filter) Filter {
+	return &filter{
+		cond: fmt.Sprintf("(%s)", f.Cond()),
+		args: f.Args(),
+		and:  []Filter{f},
+	}
+}
+
+// pinned returns the value an Eq of the conjunction pins column to
+func (f *filter) pinned(column string) (interface{}, bool) {
+	for _, and := range f.and {
+		if pinner, ok := and.(pinner); ok {
+			if value, ok := pinner.pinned(column); ok {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (f *filter) Cond() string {
+	return f.cond
+}
+
+func (f *filter) Args() []interface{} {
+	return f.args
+}
+
+func (f *filter) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(f.Args()))
+	conds = append(conds, f.Cond())
+	args = append(args, f.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{f}, ands...),
+	}
+}
+
+func (f *filter) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(f.Args()))
+	conds = append(conds, f.Cond())
+	args = append(args, f.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// Raw Raw
+func Raw(cond string, args ...interface{}) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("(%s)", cond),
+		args: args,
+	}
+}
+
+// Not Not
+func Not(f Filter) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("not (%s)", f.Cond()),
+		args: f.Args(),
+	}
+}
+
+// Subquery Subquery
+type Subquery interface {
+	SQL() string
+	Args() []interface{}
+}
+
+type subquery struct {
+	sql  string
+	args []interface{}
+}
+
+func (q *subquery) SQL() string {
+	return q.sql
+}
+
+func (q *subquery) Args() []interface{} {
+	return q.args
+}
+
+// Exists Exists
+func Exists(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("exists (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// NotExists NotExists
+func NotExists(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("not exists (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+type options struct {
+	table         string
+	sorterBuilder SorterBuilder
+	paginate      *paginate
+	lockMode      LockMode
+	distinct      bool
+	withTrashed   bool
+	onlyTrashed   bool
+	preloads      []func(tx, context.Context, []*Note) error
+}
+
+type paginate struct {
+	offset int64
+	size   int
+}
+
+// Option Option
+type Option func(*options)
+
+// WithPaginate WithPaginate
+func WithPaginate(offset int64, size int) Option {
+	return func(o *options) {
+		curPaginate := o.paginate
+		if curPaginate == nil {
+			curPaginate = &paginate{}
+			o.paginate = curPaginate
+		}
+		curPaginate.offset = offset
+		curPaginate.size = size
+	}
+}
+
+// WithSorterBuilder WithSorterBuilder
+func WithSorterBuilder(sorterBuilder SorterBuilder) Option {
+	return func(o *options) {
+		o.sorterBuilder = sorterBuilder
+	}
+}
+
+// WithLock WithLock
+func WithLock() Option {
+	return WithLockMode(LockForUpdate)
+}
+
+// WithLockMode WithLockMode
+func WithLockMode(lockMode LockMode) Option {
+	return func(o *options) {
+		o.lockMode = lockMode
+	}
+}
+
+// LockMode LockMode
+type LockMode int
+
+// LockMode
+const (
+	LockNone LockMode = iota
+	LockForUpdate
+	LockForUpdateNoWait
+	LockForUpdateSkipLocked
+	LockForShare
+	LockForShareNoWait
+	LockForShareSkipLocked
+)
+
+// ErrLockOutsideTx ErrLockOutsideTx
+var ErrLockOutsideTx = errors.New("lock outside tx")
+
+// ErrUnsupportedLockMode ErrUnsupportedLockMode
+var ErrUnsupportedLockMode = errors.New("unsupported lock mode")
+
+// Dialect Dialect
+type Dialect int
+
+// Dialect
+const (
+	DialectMySQL Dialect = iota
+	DialectMySQL57
+)
+
+func (d Dialect) lockClause(lockMode LockMode) (string, error) {
+	if d == DialectMySQL57 {
+		switch lockMode {
+		case LockForUpdate:
+			return " for update ", nil
+		case LockForShare:
+			return " lock in share mode ", nil
+		}
+		return "", ErrUnsupportedLockMode
+	}
+	switch lockMode {
+	case LockForUpdate:
+		return " for update ", nil
+	case LockForUpdateNoWait:
+		return " for update nowait ", nil
+	case LockForUpdateSkipLocked:
+		return " for update skip locked ", nil
+	case LockForShare:
+		return " for share ", nil
+	case LockForShareNoWait:
+		return " for share nowait ", nil
+	case LockForShareSkipLocked:
+		return " for share skip locked ", nil
+	}
+	return "", ErrUnsupportedLockMode
+}
+
+func (d Dialect) system() string {
+	return "mysql"
+}
+
+// retryable reports whether err is a deadlock or lock wait timeout
+func (d Dialect) retryable(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "Error 1213") || strings.Contains(msg, "Error 1205")
+}
+
+func (tx tx) lockClause(lockMode LockMode) (string, error) {
+	if lockMode == LockNone {
+		return "", nil
+	}
+	if !tx.inTx {
+		return "", ErrLockOutsideTx
+	}
+	return tx.config.dialect.lockClause(lockMode)
+}
+
+// WithTrashed WithTrashed
+func WithTrashed() Option {
+	return func(o *options) {
+		o.withTrashed = true
+	}
+}
+
+// OnlyTrashed OnlyTrashed
+func OnlyTrashed() Option {
+	return func(o *options) {
+		o.onlyTrashed = true
+	}
+}
+
+// scope restricts f to the rows visible under o
+func (o *options) scope(f Filter) Filter {
+	return f
+}
+
+// WithTable runs the query on the structurally identical table, which must
+// be a plain or schema qualified identifier
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// WithDistinct makes the Select projections select the distinct values, rows
+// selected whole by the primary key being distinct anyway
+func WithDistinct() Option {
+	return func(o *options) {
+		o.distinct = true
+	}
+}
+
+// selectSQL selects column from from, the distinct values only under WithDistinct
+func (o *options) selectSQL(column, from string) string {
+	if o.distinct {
+		return "select distinct " + column + " from " + from
+	}
+	return "select " + column + " from " + from
+}
+
+// WithJoinSorterBuilders WithJoinSorterBuilders
+func WithJoinSorterBuilders(joinSorterBuilders ...JoinableSorterBuilder) Option {
+	return func(o *options) {
+		result := joinSorterBuilders[0]
+		for _, joinSorterBuilder := range joinSorterBuilders[1:] {
+			result = result.Join(joinSorterBuilder)
+		}
+		o.sorterBuilder = result
+	}
+}
+
+// Sorter Sorter
+type Sorter string
+
+// SorterBuilder SorterBuilder
+type SorterBuilder interface {
+	Build() string
+}
+
+// Join Join
+func (s Sorter) Join(sorterBuilders ...SorterBuilder) JoinableSorterBuilder {
+	result := string(s)
+	for _, sorterBuilder := range sorterBuilders {
+		result += "," + sorterBuilder.Build()
+	}
+	return Sorter(result)
+}
+
+// Build Build
+func (s Sorter) Build() string {
+	return string(s)
+}
+
+// JoinableSorterBuilder JoinableSorterBuilder
+type JoinableSorterBuilder interface {
+	SorterBuilder
+	Join(...SorterBuilder) JoinableSorterBuilder
+}
+
+// ID ID
+type ID int64
+
+// Set Set
+func (n ID) Set() string {
+	return "id=?"
+}
+
+// Args Args
+func (n ID) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// ColumnID ColumnID
+const ColumnID Column = "id"
+
+// IncrID IncrID
+func IncrID(delta int64) Updater {
+	return &updater{
+		set:  "id=id+?",
+		args: []interface{}{delta},
+	}
+}
+
+// DecrID DecrID
+func DecrID(delta int64) Updater {
+	return &updater{
+		set:  "id=id-?",
+		args: []interface{}{delta},
+	}
+}
+
+// SetIDToColumn SetIDToColumn
+func SetIDToColumn(column Column) Updater {
+	return &updater{
+		set: fmt.Sprintf("id=%s", column),
+	}
+}
+
+// SetIDExpr SetIDExpr
+func SetIDExpr(expr string, args ...interface{}) Updater {
+	return &updater{
+		set:  fmt.Sprintf("id=%s", expr),
+		args: args,
+	}
+}
+
+// IDEq IDEq
+type IDEq int64
+
+// Cond Cond
+func (n IDEq) Cond() string {
+	return "note.id=?"
+}
+
+func (n IDEq) pinned(column string) (interface{}, bool) {
+	return int64(n), column == "id"
+}
+
+// Args Args
+func (n IDEq) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n IDEq) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDEq) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDNE IDNE
+type IDNE int64
+
+// Cond Cond
+func (n IDNE) Cond() string {
+	return "note.id != ?"
+}
+
+// Args Args
+func (n IDNE) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n IDNE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDNE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDBt IDBt
+type IDBt int64
+
+// Cond Cond
+func (n IDBt) Cond() string {
+	return "note.id>?"
+}
+
+// Args Args
+func (n IDBt) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n IDBt) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDBt) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDLt IDLt
+type IDLt int64
+
+// Cond Cond
+func (n IDLt) Cond() string {
+	return "note.id<?"
+}
+
+// Args Args
+func (n IDLt) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n IDLt) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDLt) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDBE IDBE
+type IDBE int64
+
+// Cond Cond
+func (n IDBE) Cond() string {
+	return "note.id>=?"
+}
+
+// Args Args
+func (n IDBE) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n IDBE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDBE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDLE IDLE
+type IDLE int64
+
+// Cond Cond
+func (n IDLE) Cond() string {
+	return "note.id<=?"
+}
+
+// Args Args
+func (n IDLE) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n IDLE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDLE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDIn IDIn
+type IDIn []int64
+
+// Cond Cond
+func (n IDIn) Cond() string {
+	placeHolders := make([]string, 0, len(n))
+	for range n {
+		placeHolders = append(placeHolders, "?")
+	}
+	return fmt.Sprintf("note.id in (%s)", strings.Join(placeHolders, ","))
+}
+
+// Args Args
+func (n IDIn) Args() []interface{} {
+	args := make([]interface{}, 0, len(n))
+	for _, each := range n {
+		args = append(args, each)
+	}
+	return args
+}
+
+// And And
+func (n IDIn) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDIn) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDNotIn IDNotIn
+type IDNotIn []int64
+
+// Cond Cond
+func (n IDNotIn) Cond() string {
+	placeHolders := make([]string, 0, len(n))
+	for range n {
+		placeHolders = append(placeHolders, "?")
+	}
+	return fmt.Sprintf("note.id not in (%s)", strings.Join(placeHolders, ","))
+}
+
+// Args Args
+func (n IDNotIn) Args() []interface{} {
+	args := make([]interface{}, 0, len(n))
+	for _, each := range n {
+		args = append(args, each)
+	}
+	return args
+}
+
+// And And
+func (n IDNotIn) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDNotIn) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// SelectID selects id from the rows of the tenant of ctx
+// visible under opts, the distinct values only under WithDistinct, on the
+// table bound by WithTable, the table resolver or the one shard filter is
+// pinned to, ErrCrossShardQuery being returned for subqueries across shards
+func (tx tx) SelectID(ctx context.Context, filter Filter, opts ...Option) (Subquery, error) {
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter, err := tx.scopeTenant(ctx, options.scope(filter))
+	if err != nil {
+		return nil, err
+	}
+	tx, err = tx.bindTable(ctx, options.table)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(shards) > 1 {
+		return nil, fmt.Errorf("%w: subquery", ErrCrossShardQuery)
+	}
+	return &subquery{
+		sql:  fmt.Sprintf("%s where %s", options.selectSQL("note.id", shards[0].from()), filter.Cond()),
+		args: filter.Args(),
+	}, nil
+}
+
+// IDInSubquery IDInSubquery
+func IDInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("note.id in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// IDNotInSubquery IDNotInSubquery
+func IDNotInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("note.id not in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// SortByID SortByID
+func SortByID(asc bool) JoinableSorterBuilder {
+	if asc {
+		return Sorter("note.id asc")
+	}
+	return Sorter("note.id desc")
+}
+
+// TenantID TenantID
+type TenantID int64
+
+// Set Set
+func (n TenantID) Set() string {
+	return "tenant_id=?"
+}
+
+// Args Args
+func (n TenantID) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// ColumnTenantID ColumnTenantID
+const ColumnTenantID Column = "tenant_id"
+
+// IncrTenantID IncrTenantID
+func IncrTenantID(delta int64) Updater {
+	return &updater{
+		set:  "tenant_id=tenant_id+?",
+		args: []interface{}{delta},
+	}
+}
+
+// DecrTenantID DecrTenantID
+func DecrTenantID(delta int64) Updater {
+	return &updater{
+		set:  "tenant_id=tenant_id-?",
+		args: []interface{}{delta},
+	}
+}
+
+// SetTenantIDToColumn SetTenantIDToColumn
+func SetTenantIDToColumn(column Column) Updater {
+	return &updater{
+		set: fmt.Sprintf("tenant_id=%s", column),
+	}
+}
+
+// SetTenantIDExpr SetTenantIDExpr
+func SetTenantIDExpr(expr string, args ...interface{}) Updater {
+	return &updater{
+		set:  fmt.Sprintf("tenant_id=%s", expr),
+		args: args,
+	}
+}
+
+// TenantIDEq TenantIDEq
+type TenantIDEq int64
+
+// Cond Cond
+func (n TenantIDEq) Cond() string {
+	return "note.tenant_id=?"
+}
+
+func (n TenantIDEq) pinned(column string) (interface{}, bool) {
+	return int64(n), column == "tenant_id"
+}
+
+// Args Args
+func (n TenantIDEq) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n TenantIDEq) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TenantIDEq) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TenantIDNE TenantIDNE
+type TenantIDNE int64
+
+// Cond Cond
+func (n TenantIDNE) Cond() string {
+	return "note.tenant_id != ?"
+}
+
+// Args Args
+func (n TenantIDNE) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n TenantIDNE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TenantIDNE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TenantIDBt TenantIDBt
+type TenantIDBt int64
+
+// Cond Cond
+func (n TenantIDBt) Cond() string {
+	return "note.tenant_id>?"
+}
+
+// Args Args
+func (n TenantIDBt) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n TenantIDBt) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TenantIDBt) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TenantIDLt TenantIDLt
+type TenantIDLt int64
+
+// Cond Cond
+func (n TenantIDLt) Cond() string {
+	return "note.tenant_id<?"
+}
+
+// Args Args
+func (n TenantIDLt) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n TenantIDLt) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TenantIDLt) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TenantIDBE TenantIDBE
+type TenantIDBE int64
+
+// Cond Cond
+func (n TenantIDBE) Cond() string {
+	return "note.tenant_id>=?"
+}
+
+// Args Args
+func (n TenantIDBE) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n TenantIDBE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TenantIDBE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TenantIDLE TenantIDLE
+type TenantIDLE int64
+
+// Cond Cond
+func (n TenantIDLE) Cond() string {
+	return "note.tenant_id<=?"
+}
+
+// Args Args
+func (n TenantIDLE) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n TenantIDLE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TenantIDLE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TenantIDIn TenantIDIn
+type TenantIDIn []int64
+
+// Cond Cond
+func (n TenantIDIn) Cond() string {
+	placeHolders := make([]string, 0, len(n))
+	for range n {
+		placeHolders = append(placeHolders, "?")
+	}
+	return fmt.Sprintf("note.tenant_id in (%s)", strings.Join(placeHolders, ","))
+}
+
+// Args Args
+func (n TenantIDIn) Args() []interface{} {
+	args := make([]interface{}, 0, len(n))
+	for _, each := range n {
+		args = append(args, each)
+	}
+	return args
+}
+
+// And And
+func (n TenantIDIn) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TenantIDIn) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TenantIDNotIn TenantIDNotIn
+type TenantIDNotIn []int64
+
+// Cond Cond
+func (n TenantIDNotIn) Cond() string {
+	placeHolders := make([]string, 0, len(n))
+	for range n {
+		placeHolders = append(placeHolders, "?")
+	}
+	return fmt.Sprintf("note.tenant_id not in (%s)", strings.Join(placeHolders, ","))
+}
+
+// Args Args
+func (n TenantIDNotIn) Args() []interface{} {
+	args := make([]interface{}, 0, len(n))
+	for _, each := range n {
+		args = append(args, each)
+	}
+	return args
+}
+
+// And And
+func (n TenantIDNotIn) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TenantIDNotIn) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// SelectTenantID selects tenant_id from the rows of the tenant of ctx
+// visible under opts, the distinct values only under WithDistinct, on the
+// table bound by WithTable, the table resolver or the one shard filter is
+// pinned to, ErrCrossShardQuery being returned for subqueries across shards
+func (tx tx) SelectTenantID(ctx context.Context, filter Filter, opts ...Option) (Subquery, error) {
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter, err := tx.scopeTenant(ctx, options.scope(filter))
+	if err != nil {
+		return nil, err
+	}
+	tx, err = tx.bindTable(ctx, options.table)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(shards) > 1 {
+		return nil, fmt.Errorf("%w: subquery", ErrCrossShardQuery)
+	}
+	return &subquery{
+		sql:  fmt.Sprintf("%s where %s", options.selectSQL("note.tenant_id", shards[0].from()), filter.Cond()),
+		args: filter.Args(),
+	}, nil
+}
+
+// TenantIDInSubquery TenantIDInSubquery
+func TenantIDInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("note.tenant_id in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// TenantIDNotInSubquery TenantIDNotInSubquery
+func TenantIDNotInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("note.tenant_id not in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// SortByTenantID SortByTenantID
+func SortByTenantID(asc bool) JoinableSorterBuilder {
+	if asc {
+		return Sorter("note.tenant_id asc")
+	}
+	return Sorter("note.tenant_id desc")
+}
+
+// Title Title
+type Title string
+
+// Set Set
+func (n Title) Set() string {
+	return "title=?"
+}
+
+// Args Args
+func (n Title) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// ColumnTitle ColumnTitle
+const ColumnTitle Column = "title"
+
+// SetTitleToColumn SetTitleToColumn
+func SetTitleToColumn(column Column) Updater {
+	return &updater{
+		set: fmt.Sprintf("title=%s", column),
+	}
+}
+
+// SetTitleExpr SetTitleExpr
+func SetTitleExpr(expr string, args ...interface{}) Updater {
+	return &updater{
+		set:  fmt.Sprintf("title=%s", expr),
+		args: args,
+	}
+}
+
+// TitleEq TitleEq
+type TitleEq string
+
+// Cond Cond
+func (n TitleEq) Cond() string {
+	return "note.title=?"
+}
+
+func (n TitleEq) pinned(column string) (interface{}, bool) {
+	return string(n), column == "title"
+}
+
+// Args Args
+func (n TitleEq) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// And And
+func (n TitleEq) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TitleEq) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TitleNE TitleNE
+type TitleNE string
+
+// Cond Cond
+func (n TitleNE) Cond() string {
+	return "note.title != ?"
+}
+
+// Args Args
+func (n TitleNE) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// And And
+func (n TitleNE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TitleNE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TitleBt TitleBt
+type TitleBt string
+
+// Cond Cond
+func (n TitleBt) Cond() string {
+	return "note.title>?"
+}
+
+// Args Args
+func (n TitleBt) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// And And
+func (n TitleBt) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TitleBt) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TitleLt TitleLt
+type TitleLt string
+
+// Cond Cond
+func (n TitleLt) Cond() string {
+	return "note.title<?"
+}
+
+// Args Args
+func (n TitleLt) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// And And
+func (n TitleLt) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TitleLt) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TitleBE TitleBE
+type TitleBE string
+
+// Cond Cond
+func (n TitleBE) Cond() string {
+	return "note.title>=?"
+}
+
+// Args Args
+func (n TitleBE) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// And And
+func (n TitleBE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TitleBE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TitleLE TitleLE
+type TitleLE string
+
+// Cond Cond
+func (n TitleLE) Cond() string {
+	return "note.title<=?"
+}
+
+// Args Args
+func (n TitleLE) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// And And
+func (n TitleLE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TitleLE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TitleIn TitleIn
+type TitleIn []string
+
+// Cond Cond
+func (n TitleIn) Cond() string {
+	placeHolders := make([]string, 0, len(n))
+	for range n {
+		placeHolders = append(placeHolders, "?")
+	}
+	return fmt.Sprintf("note.title in (%s)", strings.Join(placeHolders, ","))
+}
+
+// Args Args
+func (n TitleIn) Args() []interface{} {
+	args := make([]interface{}, 0, len(n))
+	for _, each := range n {
+		args = append(args, each)
+	}
+	return args
+}
+
+// And And
+func (n TitleIn) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TitleIn) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// TitleNotIn TitleNotIn
+type TitleNotIn []string
+
+// Cond Cond
+func (n TitleNotIn) Cond() string {
+	placeHolders := make([]string, 0, len(n))
+	for range n {
+		placeHolders = append(placeHolders, "?")
+	}
+	return fmt.Sprintf("note.title not in (%s)", strings.Join(placeHolders, ","))
+}
+
+// Args Args
+func (n TitleNotIn) Args() []interface{} {
+	args := make([]interface{}, 0, len(n))
+	for _, each := range n {
+		args = append(args, each)
+	}
+	return args
+}
+
+// And And
+func (n TitleNotIn) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n TitleNotIn) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// SelectTitle selects title from the rows of the tenant of ctx
+// visible under opts, the distinct values only under WithDistinct, on the
+// table bound by WithTable, the table resolver or the one shard filter is
+// pinned to, ErrCrossShardQuery being returned for subqueries across shards
+func (tx tx) SelectTitle(ctx context.Context, filter Filter, opts ...Option) (Subquery, error) {
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter, err := tx.scopeTenant(ctx, options.scope(filter))
+	if err != nil {
+		return nil, err
+	}
+	tx, err = tx.bindTable(ctx, options.table)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(shards) > 1 {
+		return nil, fmt.Errorf("%w: subquery", ErrCrossShardQuery)
+	}
+	return &subquery{
+		sql:  fmt.Sprintf("%s where %s", options.selectSQL("note.title", shards[0].from()), filter.Cond()),
+		args: filter.Args(),
+	}, nil
+}
+
+// TitleInSubquery TitleInSubquery
+func TitleInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("note.title in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// TitleNotInSubquery TitleNotInSubquery
+func TitleNotInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("note.title not in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// SortByTitle SortByTitle
+func SortByTitle(asc bool) JoinableSorterBuilder {
+	if asc {
+		return Sorter("note.title asc")
+	}
+	return Sorter("note.title desc")
+}
@@ -0,0 +1,149 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/wwq1988/gorm/testdata/fakedb"
+)
+
+// recordQueries records the queries passed through the interceptors
+func recordQueries(queries *[]*Query) RepoOption {
+	return WithInterceptors(func(ctx context.Context, query *Query, next Invoker) error {
+		err := next(ctx, query)
+		*queries = append(*queries, query)
+		return err
+	})
+}
+
+// orFilter is a Filter implemented outside the repo, its cond not parenthesized
+type orFilter string
+
+func (f orFilter) Cond() string {
+	return string(f)
+}
+
+func (f orFilter) Args() []interface{} {
+	return []interface{}{"a", "b"}
+}
+
+func TestTenant(t *testing.T) {
+	db := fakedb.New().Open()
+	defer db.Close()
+	var queries []*Query
+	repo := NewRepo(db, recordQueries(&queries))
+
+	if _, err := repo.Find(context.Background(), TitleEq("a")); err != ErrMissingTenant {
+		t.Fatalf("unexpected find err without tenant:%#v", err)
+	}
+	if _, err := repo.Create(context.Background(), &Note{Title: "a"}); err != ErrMissingTenant {
+		t.Fatalf("unexpected create err without tenant:%#v", err)
+	}
+	if _, err := repo.Delete(context.Background(), TitleEq("a")); err != ErrMissingTenant {
+		t.Fatalf("unexpected delete err without tenant:%#v", err)
+	}
+	if len(queries) != 0 {
+		t.Fatalf("unexpected queries without tenant:%#v", queries)
+	}
+
+	ctx := ContextWithTenant(context.Background(), 7)
+	if _, err := repo.Find(ctx, TitleEq("a")); err != nil {
+		t.Fatalf("unexpected find err:%#v", err)
+	}
+	if _, err := repo.Count(ctx, nil); err != nil {
+		t.Fatalf("unexpected count err:%#v", err)
+	}
+	if _, err := repo.Update(ctx, TitleEq("a"), Title("b")); err != nil {
+		t.Fatalf("unexpected update err:%#v", err)
+	}
+	if _, err := repo.Delete(ctx, IDEq(1)); err != nil {
+		t.Fatalf("unexpected delete err:%#v", err)
+	}
+	note := &Note{TenantID: 8, Title: "a"}
+	if _, err := repo.Create(ctx, note); err != nil {
+		t.Fatalf("unexpected create err:%#v", err)
+	}
+	if note.TenantID != 7 {
+		t.Fatalf("unexpected created tenant:%#v", note.TenantID)
+	}
+	expected := []struct {
+		sql  string
+		args []interface{}
+	}{
+		{"select note.id,note.tenant_id,note.title from note where (note.tenant_id=? and (note.title=?))", []interface{}{int64(7), "a"}},
+		{"select count(*) from note where note.tenant_id=?", []interface{}{int64(7)}},
+		{"update note set title=? where (note.tenant_id=? and (note.title=?))", []interface{}{"b", int64(7), "a"}},
+		{"delete from note where (note.tenant_id=? and (note.id=?))", []interface{}{int64(7), int64(1)}},
+		{"insert into note(tenant_id,title) values (?,?)", []interface{}{int64(7), "a"}},
+	}
+	if len(queries) != len(expected) {
+		t.Fatalf("unexpected queries:%#v", queries)
+	}
+	for i, query := range queries {
+		if query.SQL != expected[i].sql || !reflect.DeepEqual(query.Args, expected[i].args) {
+			t.Fatalf("unexpected query %d:%s %#v", i, query.SQL, query.Args)
+		}
+	}
+
+	for _, updater := range []Updater{
+		TenantID(8), SetTenantIDToColumn(ColumnID), SetTenantIDExpr("?", 8), IncrTenantID(1),
+		RawUpdater("tenant_id = ?", 8), RawUpdater("title=?, tenant_id=?", "x", 8), RawUpdater("note.\x60TENANT_ID\x60=?", 8),
+	} {
+		if _, err := repo.Update(ctx, IDEq(1), Title("b"), updater); err != ErrTenantUpdate {
+			t.Fatalf("unexpected tenant update err:%#v", err)
+		}
+	}
+	if _, err := repo.SelectID(context.Background(), TitleEq("a")); err != ErrMissingTenant {
+		t.Fatalf("unexpected select err without tenant:%#v", err)
+	}
+	subquery, err := repo.SelectID(ctx, nil)
+	if err != nil {
+		t.Fatalf("unexpected select err:%#v", err)
+	}
+	if subquery.SQL() != "select note.id from note where note.tenant_id=?" || !reflect.DeepEqual(subquery.Args(), []interface{}{int64(7)}) {
+		t.Fatalf("unexpected subquery:%s %#v", subquery.SQL(), subquery.Args())
+	}
+	subquery, err = repo.SelectID(ctx, orFilter("note.title=? or note.title=?"))
+	if err != nil {
+		t.Fatalf("unexpected select err:%#v", err)
+	}
+	if subquery.SQL() != "select note.id from note where (note.tenant_id=? and (note.title=? or note.title=?))" {
+		t.Fatalf("unexpected subquery with or:%s", subquery.SQL())
+	}
+	subquery, err = repo.SelectID(ctx, nil, WithTable("note_2026"))
+	if err != nil {
+		t.Fatalf("unexpected select err:%#v", err)
+	}
+	if subquery.SQL() != "select note.id from note_2026 note where note.tenant_id=?" {
+		t.Fatalf("unexpected subquery on table:%s", subquery.SQL())
+	}
+	sharded := NewRepo(db, WithSharding(ModShardStrategy("id", 2), TableShards(2)))
+	if _, err := sharded.SelectID(ctx, nil); !errors.Is(err, ErrCrossShardQuery) {
+		t.Fatalf("unexpected select err across shards:%#v", err)
+	}
+	subquery, err = sharded.SelectID(ctx, IDEq(3))
+	if err != nil {
+		t.Fatalf("unexpected select err:%#v", err)
+	}
+	if subquery.SQL() != "select note.id from note_01 note where (note.tenant_id=? and (note.id=?))" {
+		t.Fatalf("unexpected subquery on shard:%s", subquery.SQL())
+	}
+	if len(queries) != len(expected) {
+		t.Fatalf("unexpected queries:%#v", queries)
+	}
+
+	resolved := NewRepo(db, WithTenantResolver(func(ctx context.Context) (int64, bool) {
+		return 9, true
+	}))
+	notes := []*Note{&Note{Title: "a"}, &Note{Title: "b"}}
+	if err := resolved.BatchCreate(context.Background(), notes); err != nil {
+		t.Fatalf("unexpected batch create err:%#v", err)
+	}
+	for _, note := range notes {
+		if note.TenantID != 9 {
+			t.Fatalf("unexpected batch created tenant:%#v", note.TenantID)
+		}
+	}
+}
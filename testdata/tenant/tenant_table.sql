@@ -0,0 +1,7 @@
+CREATE TABLE note (
+	id bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+	tenant_id bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT '租户',
+	title varchar(100) NOT NULL DEFAULT '' COMMENT '标题',
+	PRIMARY KEY (id),
+	KEY idx_tenant_id (tenant_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -1,11 +1,17 @@
 package testdata
 
-import "time"
+import (
+	"database/sql"
+	"time"
+)
 
 // User user
 type User struct {
-	ID        int64     `gorm:"id"`
-	Name      string    `gorm:"name"`
-	Password  string    `gorm:"password"`
-	CreatedAt time.Time `gorm:"created_at"`
+	ID        int64        `gorm:"id"`
+	Name      string       `gorm:"name"`
+	Password  string       `gorm:"password,sensitive"`
+	CreatedAt time.Time    `gorm:"created_at,autocreatetime"`
+	UpdatedAt time.Time    `gorm:"updated_at,autoupdatetime"`
+	Version   int64        `gorm:"version,version"`
+	DeletedAt sql.NullTime `gorm:"deleted_at,softdelete"`
 }
@@ -1,11 +1,22 @@
 package testdata
 
 import (
+	"container/list"
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,23 +25,115 @@ type TxHandler func(ctx context.Context, tx Tx) error
 
 // Repo Repo
 type Repo interface {
-	InTx(ctx context.Context, txHandler TxHandler) error
+	InReadOnlyTx(ctx context.Context, txHandler TxHandler, opts ...TxOption) error
+	InSQLTx(ctx context.Context, sqlTxHandler SQLTxHandler, opts ...TxOption) error
+	BindTx(sqlTx *sql.Tx) Tx
+	Executor() Executor
 	Tx
 }
 
+// SQLTxHandler SQLTxHandler
+type SQLTxHandler func(ctx context.Context, sqlTx *sql.Tx) error
+
+type txOptions struct {
+	sql.TxOptions
+	retryPolicy *RetryPolicy
+}
+
+// TxOption TxOption
+type TxOption func(*txOptions)
+
+// WithTxOptions WithTxOptions, a nil sqlTxOptions meaning the defaults
+func WithTxOptions(sqlTxOptions *sql.TxOptions) TxOption {
+	return func(o *txOptions) {
+		if sqlTxOptions == nil {
+			o.TxOptions = sql.TxOptions{}
+			return
+		}
+		o.TxOptions = *sqlTxOptions
+	}
+}
+
+// WithIsolation WithIsolation
+func WithIsolation(isolation sql.IsolationLevel) TxOption {
+	return func(o *txOptions) {
+		o.Isolation = isolation
+	}
+}
+
+// WithReadOnly WithReadOnly
+func WithReadOnly() TxOption {
+	return func(o *txOptions) {
+		o.ReadOnly = true
+	}
+}
+
+// WithRetry WithRetry
+func WithRetry(retryPolicy RetryPolicy) TxOption {
+	return func(o *txOptions) {
+		o.retryPolicy = &retryPolicy
+	}
+}
+
+// RetryPolicy re-runs a whole TxHandler in a fresh transaction when it
+// fails with an error Retryable reports true for, the dialect's deadlock
+// and lock wait timeout errors by default
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Retryable   func(error) bool
+}
+
+const maxBackoff = time.Duration(1<<63 - 1)
+
+// backoff returns the exponential backoff with jitter before the attempt+1th run
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.BaseBackoff
+	// doubling stops at MaxBackoff or before overflowing
+	for i := 1; i < attempt && backoff > 0 && backoff <= maxBackoff/2; i++ {
+		if p.MaxBackoff > 0 && backoff >= p.MaxBackoff {
+			break
+		}
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
 // Tx Tx
 type Tx interface {
+	InTx(ctx context.Context, txHandler TxHandler, opts ...TxOption) error
 	Find(ctx context.Context, filter Filter, opts ...Option) ([]*User, error)
 	FindOne(ctx context.Context, filter Filter, opts ...Option) (*User, error)
+	Count(ctx context.Context, filter Filter, opts ...Option) (int64, error)
 	Delete(ctx context.Context, filter Filter) (int64, error)
+	Restore(ctx context.Context, filter Filter) (int64, error)
+	HardDelete(ctx context.Context, filter Filter) (int64, error)
 	Update(ctx context.Context, filter Filter, updaters ...Updater) (int64, error)
+	UpdateByID(ctx context.Context, id int64, version int64, updaters ...Updater) (int64, error)
+	Save(ctx context.Context, obj *User) (int64, error)
 	Create(ctx context.Context, obj *User) (int64, error)
 	BatchCreate(ctx context.Context, objs []*User) error
+	DistinctID(ctx context.Context, filter Filter) ([]int64, error)
+	DistinctName(ctx context.Context, filter Filter) ([]string, error)
+	DistinctPassword(ctx context.Context, filter Filter) ([]string, error)
+	DistinctCreatedAt(ctx context.Context, filter Filter) ([]time.Time, error)
+	DistinctUpdatedAt(ctx context.Context, filter Filter) ([]time.Time, error)
+	DistinctVersion(ctx context.Context, filter Filter) ([]int64, error)
+	DistinctDeletedAt(ctx context.Context, filter Filter) ([]sql.NullTime, error)
 }
 
-type sqlCommon interface {
+// Executor is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type Executor interface {
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
-	QueryContext(ctx context.Context, query string, ags ...interface{}) (*sql.Rows, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
@@ -43,653 +146,4155 @@ type repo struct {
 }
 
 type tx struct {
-	db sqlCommon
+	db       Executor
+	config   *config
+	inTx     bool
+	replicas *replicaSet
+	shard    *tableShard
 }
 
-// NewUserRepo NewUserRepo
-func NewRepo(db *sql.DB) Repo {
-	return &repo{
-		tx{db: db},
+type config struct {
+	now           func() time.Time
+	dialect       Dialect
+	interceptors  []Interceptor
+	tracer        Tracer
+	stmtCacheSize int
+	stmtCache     *stmtCache
+	sharding      *sharding
+	tableResolver func(ctx context.Context) string
+}
+
+// RepoOption RepoOption
+type RepoOption func(*config)
+
+// WithClock WithClock
+func WithClock(now func() time.Time) RepoOption {
+	return func(c *config) {
+		c.now = now
 	}
 }
 
-func (rp repo) InTx(ctx context.Context, txHandler TxHandler) error {
-	db, ok := rp.db.(sqlDB)
-	if !ok {
-		return errors.New("do not support tx")
+// WithDialect WithDialect
+func WithDialect(dialect Dialect) RepoOption {
+	return func(c *config) {
+		c.dialect = dialect
 	}
-	dbTx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
+}
+
+// WithInterceptors appends interceptors run around every statement, the
+// first one being the outermost
+func WithInterceptors(interceptors ...Interceptor) RepoOption {
+	return func(c *config) {
+		c.interceptors = append(c.interceptors, interceptors...)
 	}
-	defer dbTx.Rollback()
-	tx := &tx{dbTx}
-	if err := txHandler(ctx, tx); err != nil {
-		return err
+}
+
+// WithTracer starts a span around every generated method and transaction,
+// the span of each statement they run being a child of theirs
+func WithTracer(tracer Tracer) RepoOption {
+	return func(c *config) {
+		c.tracer = tracer
+		c.interceptors = append(c.interceptors, func(ctx context.Context, query *Query, next Invoker) error {
+			ctx, span := c.startNamedSpan(ctx, query.Operation+" "+query.Table, query.Operation)
+			span.SetAttribute("db.sql.table", query.Table)
+			span.SetAttribute("db.statement", query.SQL)
+			err := next(ctx, query)
+			span.SetAttribute("db.rows_affected", query.RowsAffected)
+			endSpan(span, err)
+			return err
+		})
 	}
-	if err := dbTx.Commit(); err != nil {
+}
+
+// WithMetrics reports every statement to metrics
+func WithMetrics(metrics Metrics) RepoOption {
+	return WithInterceptors(func(ctx context.Context, query *Query, next Invoker) error {
+		err := next(ctx, query)
+		metrics.ObserveQuery(query.Entity, query.Operation, query.Duration, err)
 		return err
+	})
+}
+
+// WithStmtCache caches up to size prepared statements keyed on their sql,
+// transactions run them through Tx.StmtContext, the pagination being bound
+// as args and the statements with in lists or several rows not being cached
+func WithStmtCache(size int) RepoOption {
+	return func(c *config) {
+		c.stmtCacheSize = size
 	}
-	return nil
 }
 
-// Find Find
-func (tx tx) Find(ctx context.Context, filter Filter, opts ...Option) ([]*User, error) {
-	options := &options{}
+// WithTableResolver runs the statements on the structurally identical table
+// tableResolver returns for their ctx, the table of the repo being used
+// when it returns "" and the statements failing with ErrInvalidTable when
+// it is not an identifier
+func WithTableResolver(tableResolver func(ctx context.Context) string) RepoOption {
+	return func(c *config) {
+		c.tableResolver = tableResolver
+	}
+}
+
+func newConfig(executor Executor, opts []RepoOption) *config {
+	config := &config{
+		now: time.Now,
+	}
 	for _, opt := range opts {
-		opt(options)
+		opt(config)
 	}
-	sortStr := ""
-	if options.sorterBuilder != nil {
-		sortStr = fmt.Sprintf(" order by %s ", options.sorterBuilder.Build())
+	if preparer, ok := executor.(preparer); ok && config.stmtCacheSize > 0 {
+		config.stmtCache = newStmtCache(preparer, config.stmtCacheSize)
 	}
-
-	paginate := ""
-	if options.paginate != nil {
-		paginate = fmt.Sprintf(" inner join (select id from user %s limit %d, %d) tmp on user.id = tmp.id ", sortStr, options.paginate.offset, options.paginate.size)
-		if filter != nil && filter.Cond() != "" {
-			paginate = fmt.Sprintf(" inner join (select id from user where %s %s limit %d, %d) tmp on user.id = tmp.id ", filter.Cond(), sortStr, options.paginate.offset, options.paginate.size)
+	if config.sharding != nil && config.stmtCacheSize > 0 {
+		for _, shard := range config.sharding.shards {
+			if preparer, ok := shard.db.(preparer); ok {
+				shard.stmtCache = newStmtCache(preparer, config.stmtCacheSize)
+			}
 		}
 	}
+	return config
+}
+
+// NewRepo NewRepo
+func NewRepo(db *sql.DB, opts ...RepoOption) Repo {
+	return NewRepoFromExecutor(db, opts...)
+}
 
-	withLock := ""
-	if options.withLock {
-		withLock = " for update "
+// NewRepoFromExecutor returns a Repo running its statements on executor,
+// InTx nests with savepoints when executor is a *sql.Tx
+func NewRepoFromExecutor(executor Executor, opts ...RepoOption) Repo {
+	_, inTx := executor.(*sql.Tx)
+	return &repo{
+		tx{db: executor, config: newConfig(executor, opts), inTx: inTx},
 	}
+}
 
-	var rows *sql.Rows
-	var err error
-	if filter == nil || filter.Cond() == "" {
-		sql := fmt.Sprintf("select user.id,name,password,created_at from user%s%s%s", sortStr, paginate, withLock)
-		if paginate != "" {
-			sql = fmt.Sprintf("select user.id,name,password,created_at from user%s%s", paginate, withLock)
+// Replica is a read only pool picked by a weighted round-robin, a Weight
+// below 1 counting as 1
+type Replica struct {
+	DB     *sql.DB
+	Weight int
+}
+
+type replica struct {
+	db        Executor
+	stmtCache *stmtCache
+}
+
+type replicaSet struct {
+	replicas []*replica
+	next     uint64
+}
+
+func (s *replicaSet) pick() *replica {
+	next := atomic.AddUint64(&s.next, 1) - 1
+	return s.replicas[next%uint64(len(s.replicas))]
+}
+
+// NewRepoWithReplicas returns a Repo running the reads made outside of a
+// transaction on replicas, and the writes, the transactions and the reads
+// of a ForcePrimary context on primary
+func NewRepoWithReplicas(primary *sql.DB, replicas []Replica, opts ...RepoOption) Repo {
+	config := newConfig(primary, opts)
+	var readers *replicaSet
+	for _, each := range replicas {
+		if readers == nil {
+			readers = &replicaSet{}
 		}
-		rows, err = tx.db.QueryContext(ctx, sql)
-	} else {
-		sql := fmt.Sprintf("select user.id,name,password,created_at from user where %s%s%s%s", filter.Cond(), sortStr, paginate, withLock)
-		if paginate != "" {
-			sql = fmt.Sprintf("select user.id,name,password,created_at from user %s%s", paginate, withLock)
+		replica := &replica{db: each.DB}
+		if config.stmtCacheSize > 0 {
+			replica.stmtCache = newStmtCache(each.DB, config.stmtCacheSize)
+		}
+		for i := 0; i == 0 || i < each.Weight; i++ {
+			readers.replicas = append(readers.replicas, replica)
 		}
-		rows, err = tx.db.QueryContext(ctx, sql, filter.Args()...)
 	}
-	if err != nil {
-		return nil, err
+	return &repo{
+		tx{db: primary, config: config, replicas: readers},
 	}
-	defer rows.Close()
-	var results []*User
-	for rows.Next() {
-		result := &User{}
-		if err := rows.Scan(&result.ID, &result.Name, &result.Password, &result.CreatedAt); err != nil {
-			return nil, err
+}
+
+type forcePrimaryKey struct{}
+
+// ForcePrimary returns a ctx whose reads run on the primary, so that they
+// see the writes made just before
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+// writer returns the executor and the statement cache running the writes of tx
+func (tx tx) writer() (Executor, *stmtCache) {
+	if tx.shard != nil && tx.shard.db != nil {
+		return tx.shard.db, tx.shard.stmtCache
+	}
+	return tx.db, tx.config.stmtCache
+}
+
+// reader returns the executor and the statement cache running the reads of ctx
+func (tx tx) reader(ctx context.Context) (Executor, *stmtCache) {
+	if tx.replicas == nil || tx.inTx || ctx.Value(forcePrimaryKey{}) != nil {
+		return tx.writer()
+	}
+	replica := tx.replicas.pick()
+	return replica.db, replica.stmtCache
+}
+
+// ShardStrategy maps the value of the shard key column to the index of its shard
+type ShardStrategy interface {
+	Column() string
+	Shard(key interface{}) int
+}
+
+// Shard is a table holding a part of the rows, on DB or on the db of the
+// repo when DB is nil
+type Shard struct {
+	DB    *sql.DB
+	Table string
+}
+
+// shardTx is a tx bound to a shard, named apart as the receivers of tx
+// shadow its type
+type shardTx = tx
+
+type tableShard struct {
+	table     string
+	db        Executor
+	stmtCache *stmtCache
+}
+
+type sharding struct {
+	strategy ShardStrategy
+	shards   []*tableShard
+}
+
+// ErrCrossShardQuery ErrCrossShardQuery
+var ErrCrossShardQuery = errors.New("unsupported query across shards")
+
+// ErrMissingShardKey is returned when creating or saving a row whose shard
+// key is the zero value
+var ErrMissingShardKey = errors.New("missing shard key")
+
+// WithSharding splits the table across shards by strategy, a statement runs
+// on a single shard when its filter pins the shard key with an Eq and on
+// every shard otherwise, Find merging the rows in the order of the sorters
+// built with SortBy before paginating them. The strings are merged in byte
+// order, which may differ from the collation of the table, so paginating
+// across shards by a string column is rejected. When sharding on the
+// primary key the rows are created with the key set by the caller, as the
+// auto increments of the shards would collide
+func WithSharding(strategy ShardStrategy, shards []Shard) RepoOption {
+	return func(c *config) {
+		c.sharding = &sharding{strategy: strategy}
+		for _, each := range shards {
+			shard := &tableShard{table: each.Table}
+			if each.DB != nil {
+				shard.db = each.DB
+			}
+			c.sharding.shards = append(c.sharding.shards, shard)
 		}
-		results = append(results, result)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+}
+
+// TableShards returns n shards on the db of the repo named after the table
+// suffixed with _00, _01...
+func TableShards(n int) []Shard {
+	shards := make([]Shard, 0, n)
+	for i := 0; i < n; i++ {
+		shards = append(shards, Shard{Table: fmt.Sprintf("user_%02d", i)})
 	}
-	return results, nil
+	return shards
 }
 
-// FindOne FindOne
-func (tx tx) FindOne(ctx context.Context, filter Filter, opts ...Option) (*User, error) {
-	options := &options{}
-	for _, opt := range opts {
-		opt(options)
+type modShardStrategy struct {
+	column string
+	shards int
+}
+
+// ModShardStrategy shards on column by the integer keys modulo shards, the
+// other keys being hashed
+func ModShardStrategy(column string, shards int) ShardStrategy {
+	return modShardStrategy{column: column, shards: shards}
+}
+
+// Column Column
+func (s modShardStrategy) Column() string {
+	return s.column
+}
+
+// Shard Shard
+func (s modShardStrategy) Shard(key interface{}) int {
+	value := reflect.ValueOf(key)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		shards := int64(s.shards)
+		return int((value.Int()%shards + shards) % shards)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(value.Uint() % uint64(s.shards))
 	}
+	hash := fnv.New32a()
+	fmt.Fprint(hash, key)
+	return int(hash.Sum32() % uint32(s.shards))
+}
 
-	sortStr := ""
-	if options.sorterBuilder != nil {
-		sortStr = fmt.Sprintf(" order by %s ", options.sorterBuilder.Build())
+// from returns the table of the statements of tx, a shard being aliased as
+// the table so that the qualified columns of the filters still apply
+func (tx tx) from() string {
+	if tx.shard == nil {
+		return "user"
 	}
+	return tx.shard.table + " user"
+}
 
-	paginate := ""
-	if options.paginate != nil {
-		paginate = fmt.Sprintf(" limit %d, %d ", options.paginate.offset, options.paginate.size)
+// tableName returns the physical table of tx
+func (tx tx) tableName() string {
+	if tx.shard == nil {
+		return "user"
 	}
+	return tx.shard.table
+}
 
-	withLock := ""
-	if options.withLock {
-		withLock = " for update "
+func (tx tx) deleteFrom() string {
+	if tx.shard == nil {
+		return "delete from user"
 	}
+	return "delete user from " + tx.from()
+}
 
-	var row *sql.Row
-	if filter == nil || filter.Cond() == "" {
-		sql := fmt.Sprintf("select user.id,name,password,created_at from user%s%s%s", sortStr, paginate, withLock)
-		if paginate != "" {
-			sql = fmt.Sprintf("select user.id,name,password,created_at from user%s%s", paginate, withLock)
-		}
-		row = tx.db.QueryRowContext(ctx, sql)
-	} else {
-		sql := fmt.Sprintf("select user.id,name,password,created_at from user where %s%s%s%s", filter.Cond(), sortStr, paginate, withLock)
-		if paginate != "" {
-			sql = fmt.Sprintf("select user.id,name,password,created_at from user %s%s", paginate, withLock)
-		}
-		row = tx.db.QueryRowContext(ctx, sql, filter.Args()...)
+// ErrInvalidTable is returned for a table given to WithTable or returned by
+// the table resolver which is not a plain or schema qualified identifier
+var ErrInvalidTable = errors.New("invalid table")
+
+var tablePattern = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*(\\.[A-Za-z_][A-Za-z0-9_]*)?$")
+
+// bindTable returns tx bound to table or to the table resolved for ctx,
+// which take precedence over the shards
+func (tx tx) bindTable(ctx context.Context, table string) (tx, error) {
+	if table == "" && tx.config.tableResolver != nil {
+		table = tx.config.tableResolver(ctx)
 	}
-	result := &User{}
-	if err := row.Scan(&result.ID, &result.Name, &result.Password, &result.CreatedAt); err != nil {
-		return nil, err
+	if table == "" || tx.shard != nil {
+		return tx, nil
 	}
-	return result, nil
+	if !tablePattern.MatchString(table) {
+		return tx, fmt.Errorf("%w: %q", ErrInvalidTable, table)
+	}
+	bound := tx
+	bound.shard = &tableShard{table: table}
+	return bound, nil
 }
 
-// Delete Delete
-func (tx tx) Delete(ctx context.Context, filter Filter) (int64, error) {
-	var result sql.Result
-	var err error
-	if filter == nil || filter.Cond() == "" {
-		result, err = tx.db.ExecContext(ctx, "delete from user")
-	} else {
-		sql := fmt.Sprintf("delete from user where %s", filter.Cond())
-		result, err = tx.db.ExecContext(ctx, sql, filter.Args()...)
+// route returns tx bound to the shards a statement filtered by filter runs on
+func (tx tx) route(filter Filter) ([]shardTx, error) {
+	sharding := tx.config.sharding
+	if sharding == nil || tx.shard != nil {
+		return []shardTx{tx}, nil
 	}
-	if err != nil {
-		return 0, err
+	if pinner, ok := filter.(pinner); ok {
+		if key, ok := pinner.pinned(sharding.strategy.Column()); ok {
+			routed, err := tx.onShard(sharding.strategy.Shard(key))
+			if err != nil {
+				return nil, err
+			}
+			return []shardTx{routed}, nil
+		}
 	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return 0, err
+	shards := make([]shardTx, 0, len(sharding.shards))
+	for i := range sharding.shards {
+		routed, err := tx.onShard(i)
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, routed)
 	}
-	return rowsAffected, nil
+	return shards, nil
 }
 
-// Update Update
-func (tx tx) Update(ctx context.Context, filter Filter, updaters ...Updater) (int64, error) {
-	var result sql.Result
-	var err error
-	updateStrs := make([]string, 0, len(updaters))
-	updateArgs := make([]interface{}, 0, len(updaters))
-	for _, updater := range updaters {
-		updateStrs = append(updateStrs, updater.Set())
-		updateArgs = append(updateArgs, updater.Arg())
+// shardOf returns tx bound to the shard of obj
+func (tx tx) shardOf(obj *User) (shardTx, error) {
+	sharding := tx.config.sharding
+	if sharding == nil || tx.shard != nil {
+		return tx, nil
 	}
-	if filter == nil || filter.Cond() == "" {
-		sqlBaseStr := "update user set %s"
-		sqlStr := fmt.Sprintf(sqlBaseStr, strings.Join(updateStrs, ","))
-		result, err = tx.db.ExecContext(ctx, sqlStr, updateArgs...)
-	} else {
-		sqlBaseStr := "update user set %s where %s"
-		sqlStr := fmt.Sprintf(sqlBaseStr, strings.Join(updateStrs, ","), filter.Cond())
-		sqlArgs := append(updateArgs, filter.Args()...)
-		result, err = tx.db.ExecContext(ctx, sqlStr, sqlArgs...)
+	key, ok := shardKey(obj, sharding.strategy.Column())
+	if !ok {
+		return tx, fmt.Errorf("no shard key column %s", sharding.strategy.Column())
 	}
-	if err != nil {
-		return 0, err
+	if reflect.ValueOf(key).IsZero() {
+		return tx, fmt.Errorf("%w: %s", ErrMissingShardKey, sharding.strategy.Column())
 	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return 0, err
+	return tx.onShard(sharding.strategy.Shard(key))
+}
+
+// insertsKey reports whether the rows are sharded on the primary key, which
+// the inserts then set
+func (tx tx) insertsKey() bool {
+	return tx.config.sharding != nil && tx.config.sharding.strategy.Column() == "id"
+}
+
+func (tx tx) onShard(index int) (shardTx, error) {
+	shards := tx.config.sharding.shards
+	if index < 0 || index >= len(shards) {
+		return tx, fmt.Errorf("shard %d out of range", index)
 	}
-	return rowsAffected, nil
+	shard := shards[index]
+	if shard.db != nil && tx.inTx {
+		return tx, errors.New("do not support shard on another db in tx")
+	}
+	routed := tx
+	routed.shard = shard
+	if shard.db != nil {
+		routed.replicas = nil
+	}
+	return routed, nil
 }
 
-// Create Create
-func (tx tx) Create(ctx context.Context, obj *User) (int64, error) {
-	result, err := tx.db.ExecContext(ctx, "insert into user(name,password,created_at) values (?,?,?)", obj.Name, obj.Password, obj.CreatedAt)
+// sum runs do on the shards filter is routed to, summing their results
+func (tx tx) sum(filter Filter, do func(shard shardTx) (int64, error)) (int64, error) {
+	shards, err := tx.route(filter)
 	if err != nil {
 		return 0, err
 	}
-	lastInsertID, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
+	var total int64
+	for _, shard := range shards {
+		n, err := do(shard)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// shardKey returns the value of the column of obj
+func shardKey(obj *User, column string) (interface{}, bool) {
+	switch column {
+	case "id":
+		return obj.ID, true
+	case "name":
+		return obj.Name, true
+	case "password":
+		return obj.Password, true
+	case "created_at":
+		return obj.CreatedAt, true
+	case "updated_at":
+		return obj.UpdatedAt, true
+	case "version":
+		return obj.Version, true
+	case "deleted_at":
+		return obj.DeletedAt, true
+	}
+	return nil, false
+}
+
+// compareColumn compares a and b on column, false when column is not an
+// ordered field
+func compareColumn(a, b *User, column string) (int, bool) {
+	switch column {
+	case "id":
+		switch {
+		case a.ID < b.ID:
+			return -1, true
+		case a.ID > b.ID:
+			return 1, true
+		}
+		return 0, true
+	case "name":
+		switch {
+		case a.Name < b.Name:
+			return -1, true
+		case a.Name > b.Name:
+			return 1, true
+		}
+		return 0, true
+	case "password":
+		switch {
+		case a.Password < b.Password:
+			return -1, true
+		case a.Password > b.Password:
+			return 1, true
+		}
+		return 0, true
+	case "created_at":
+		switch {
+		case a.CreatedAt.Before(b.CreatedAt):
+			return -1, true
+		case a.CreatedAt.After(b.CreatedAt):
+			return 1, true
+		}
+		return 0, true
+	case "updated_at":
+		switch {
+		case a.UpdatedAt.Before(b.UpdatedAt):
+			return -1, true
+		case a.UpdatedAt.After(b.UpdatedAt):
+			return 1, true
+		}
+		return 0, true
+	case "version":
+		switch {
+		case a.Version < b.Version:
+			return -1, true
+		case a.Version > b.Version:
+			return 1, true
+		}
+		return 0, true
 	}
-	return lastInsertID, nil
+	return 0, false
 }
 
-// BatchCreate BatchCreate
-func (tx tx) BatchCreate(ctx context.Context, objs []*User) error {
-	sqlBaseStr := "insert into user(name,password,created_at) values %s"
-	sqlPlaceHolder := make([]string, 0, len(objs))
-	sqlArgs := make([]interface{}, 0, len(objs)*4)
-	for _, obj := range objs {
-		sqlPlaceHolder = append(sqlPlaceHolder, "(?,?,?)")
-		sqlArgs = append(sqlArgs, obj.Name, obj.Password, obj.CreatedAt)
+// stringColumn reports whether column holds strings
+func stringColumn(column string) bool {
+	switch column {
+	case "name":
+		return true
+	case "password":
+		return true
 	}
-	sqlStr := fmt.Sprintf(sqlBaseStr, strings.Join(sqlPlaceHolder, ","))
-	if _, err := tx.db.ExecContext(ctx, sqlStr, sqlArgs...); err != nil {
-		return err
+	return false
+}
+
+// lessBySorter returns the order of sorterBuilder merging the rows of several
+// shards, an error when paginated by a string column
+func lessBySorter(sorterBuilder SorterBuilder, paginated bool) (func(a, b *User) bool, error) {
+	type sortKey struct {
+		column string
+		desc   bool
 	}
-	return nil
+	var sortKeys []sortKey
+	for _, each := range strings.Split(sorterBuilder.Build(), ",") {
+		fields := strings.Fields(each)
+		if len(fields) == 0 {
+			continue
+		}
+		column := strings.TrimPrefix(fields[0], "user.")
+		if _, ok := compareColumn(&User{}, &User{}, column); !ok {
+			return nil, fmt.Errorf("%w: sort by %s", ErrCrossShardQuery, fields[0])
+		}
+		if paginated && stringColumn(column) {
+			return nil, fmt.Errorf("%w: paginate by %s", ErrCrossShardQuery, fields[0])
+		}
+		sortKeys = append(sortKeys, sortKey{column: column, desc: len(fields) > 1 && strings.EqualFold(fields[1], "desc")})
+	}
+	return func(a, b *User) bool {
+		for _, sortKey := range sortKeys {
+			cmp, _ := compareColumn(a, b, sortKey.column)
+			if sortKey.desc {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	}, nil
 }
 
-// Updater Updater
-type Updater interface {
-	Set() string
-	Arg() interface{}
+// WithTx returns a Tx bound to a transaction started elsewhere
+func WithTx(sqlTx *sql.Tx, opts ...RepoOption) Tx {
+	return &tx{db: sqlTx, config: newConfig(sqlTx, opts), inTx: true}
 }
 
-// Filter Filter
-type Filter interface {
-	Cond() string
-	Args() []interface{}
+// BindTx returns a Tx sharing the options of rp bound to a transaction started elsewhere
+func (rp repo) BindTx(sqlTx *sql.Tx) Tx {
+	return &tx{db: sqlTx, config: rp.config, inTx: true}
 }
 
-// JoinableFilter JoinableFilter
-type JoinableFilter interface {
-	Filter
-	Or(...Filter) JoinableFilter
-	And(...Filter) JoinableFilter
+// Executor returns the executor the statements of rp run on
+func (rp repo) Executor() Executor {
+	return rp.db
 }
 
-type filter struct {
-	cond string
-	args []interface{}
+// InTx InTx
+func (rp repo) InTx(ctx context.Context, txHandler TxHandler, opts ...TxOption) error {
+	return rp.InSQLTx(ctx, func(ctx context.Context, sqlTx *sql.Tx) error {
+		return txHandler(ctx, &tx{db: sqlTx, config: rp.config, inTx: true})
+	}, opts...)
 }
 
-func (f *filter) Cond() string {
-	return f.cond
+// InSQLTx runs sqlTxHandler like InTx but with the *sql.Tx itself, so that
+// the repos of other entities on the same pool can be bound to it with BindTx
+func (rp repo) InSQLTx(ctx context.Context, sqlTxHandler SQLTxHandler, opts ...TxOption) error {
+	if rp.inTx {
+		sqlTx, ok := rp.db.(*sql.Tx)
+		if !ok {
+			return errors.New("do not support tx")
+		}
+		return rp.tx.InTx(ctx, func(ctx context.Context, tx Tx) error {
+			return sqlTxHandler(ctx, sqlTx)
+		}, opts...)
+	}
+	ctx, span := rp.config.startSpan(ctx, "InTx")
+	err := rp.retryTx(ctx, sqlTxHandler, opts...)
+	endSpan(span, err)
+	return err
 }
 
-func (f *filter) Args() []interface{} {
-	return f.args
+func (rp repo) retryTx(ctx context.Context, sqlTxHandler SQLTxHandler, opts ...TxOption) error {
+	txOptions := &txOptions{}
+	for _, opt := range opts {
+		opt(txOptions)
+	}
+	retryPolicy := txOptions.retryPolicy
+	if retryPolicy == nil {
+		return rp.runTx(ctx, sqlTxHandler, &txOptions.TxOptions)
+	}
+	retryable := retryPolicy.Retryable
+	if retryable == nil {
+		retryable = rp.config.dialect.retryable
+	}
+	for attempt := 1; ; attempt++ {
+		err := rp.runTx(ctx, sqlTxHandler, &txOptions.TxOptions)
+		if err == nil || attempt >= retryPolicy.MaxAttempts || !retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(retryPolicy.backoff(attempt)):
+		}
+	}
+}
+
+func (rp repo) runTx(ctx context.Context, sqlTxHandler SQLTxHandler, txOptions *sql.TxOptions) error {
+	db, ok := rp.db.(sqlDB)
+	if !ok {
+		return errors.New("do not support tx")
+	}
+	dbTx, err := db.BeginTx(ctx, txOptions)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+	if err := sqlTxHandler(ctx, dbTx); err != nil {
+		return err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+var savepointSeq uint64
+
+// InTx InTx
+func (tx tx) InTx(ctx context.Context, txHandler TxHandler, opts ...TxOption) error {
+	if !tx.inTx {
+		return errors.New("do not support tx")
+	}
+	if len(opts) != 0 {
+		return errors.New("do not support tx options in nested tx")
+	}
+	ctx, span := tx.config.startSpan(ctx, "InTx")
+	err := tx.inSavepoint(ctx, txHandler)
+	endSpan(span, err)
+	return err
+}
+
+func (tx tx) inSavepoint(ctx context.Context, txHandler TxHandler) error {
+	savepoint := fmt.Sprintf("sp_%d", atomic.AddUint64(&savepointSeq, 1))
+	if err := tx.savepoint(ctx, "Savepoint", "savepoint "+savepoint); err != nil {
+		return err
+	}
+	if err := txHandler(ctx, tx); err != nil {
+		if rollbackErr := tx.savepoint(ctx, "RollbackToSavepoint", "rollback to savepoint "+savepoint); rollbackErr != nil {
+			return rollbackErr
+		}
+		return err
+	}
+	if err := tx.savepoint(ctx, "ReleaseSavepoint", "release savepoint "+savepoint); err != nil {
+		return err
+	}
+	return nil
+}
+
+// InReadOnlyTx InReadOnlyTx
+func (rp repo) InReadOnlyTx(ctx context.Context, txHandler TxHandler, opts ...TxOption) error {
+	return rp.InTx(ctx, txHandler, append(opts, WithReadOnly())...)
+}
+
+// Redacted is a bind arg logged as [REDACTED], the generated filters,
+// updaters and inserts wrap the args of sensitive columns with it
+type Redacted struct {
+	value interface{}
+}
+
+// Redact Redact
+func Redact(value interface{}) Redacted {
+	if redacted, ok := value.(Redacted); ok {
+		return redacted
+	}
+	return Redacted{value: value}
+}
+
+func redactAll(args []interface{}) []interface{} {
+	redacted := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		redacted = append(redacted, Redact(arg))
+	}
+	return redacted
+}
+
+// Value Value
+func (r Redacted) Value() (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(r.value)
+}
+
+// String String
+func (r Redacted) String() string {
+	return "[REDACTED]"
+}
+
+// GoString GoString
+func (r Redacted) GoString() string {
+	return r.String()
+}
+
+// Logger is satisfied by *slog.Logger
+type Logger interface {
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+type logConfig struct {
+	slowThreshold time.Duration
+}
+
+// LogOption LogOption
+type LogOption func(*logConfig)
+
+// WithSlowThreshold logs the statements running for slowThreshold or longer as warnings
+func WithSlowThreshold(slowThreshold time.Duration) LogOption {
+	return func(c *logConfig) {
+		c.slowThreshold = slowThreshold
+	}
+}
+
+// WithLogger logs every statement to logger with its placeholders and
+// args, the args of sensitive columns being redacted
+func WithLogger(logger Logger, opts ...LogOption) RepoOption {
+	logConfig := &logConfig{}
+	for _, opt := range opts {
+		opt(logConfig)
+	}
+	return WithInterceptors(func(ctx context.Context, query *Query, next Invoker) error {
+		err := next(ctx, query)
+		args := make([]interface{}, 0, len(query.Args))
+		for _, arg := range query.Args {
+			if redacted, ok := arg.(Redacted); ok {
+				arg = redacted.String()
+			}
+			args = append(args, arg)
+		}
+		attrs := []interface{}{
+			"operation", query.Operation,
+			"entity", query.Entity,
+			"table", query.Table,
+			"sql", query.SQL,
+			"args", args,
+			"duration", query.Duration,
+			"rows", query.RowsAffected,
+		}
+		switch {
+		case err != nil:
+			logger.ErrorContext(ctx, "query failed", append(attrs, "error", err)...)
+		case logConfig.slowThreshold > 0 && query.Duration >= logConfig.slowThreshold:
+			logger.WarnContext(ctx, "slow query", attrs...)
+		default:
+			logger.InfoContext(ctx, "query", attrs...)
+		}
+		return err
+	})
+}
+
+// Tracer starts the spans of the repo, it is meant to be backed by an
+// OpenTelemetry tracer or by a SpanRecorder in tests
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span Span
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+
+func (noopSpan) RecordError(err error) {}
+
+func (noopSpan) End() {}
+
+// startSpan starts the span of the generated method operation
+func (c *config) startSpan(ctx context.Context, operation string) (context.Context, Span) {
+	return c.startNamedSpan(ctx, "User."+operation, operation)
+}
+
+// startNamedSpan starts the span name of operation with the db attributes of the repo
+func (c *config) startNamedSpan(ctx context.Context, name string, operation string) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := c.tracer.Start(ctx, name)
+	span.SetAttribute("db.system", c.dialect.system())
+	span.SetAttribute("db.operation", operation)
+	return ctx, span
+}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// SpanRecorder is an in-memory Tracer keeping the ended spans
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan RecordedSpan
+type RecordedSpan struct {
+	Name       string
+	Parent     *RecordedSpan
+	Attributes map[string]interface{}
+	Err        error
+	recorder   *SpanRecorder
+}
+
+type recordedSpanKey struct{}
+
+// Start Start
+func (r *SpanRecorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &RecordedSpan{
+		Name:       name,
+		Attributes: make(map[string]interface{}),
+		recorder:   r,
+	}
+	span.Parent, _ = ctx.Value(recordedSpanKey{}).(*RecordedSpan)
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+// Spans returns the ended spans in the order they ended
+func (r *SpanRecorder) Spans() []*RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*RecordedSpan(nil), r.spans...)
+}
+
+// SetAttribute SetAttribute
+func (s *RecordedSpan) SetAttribute(key string, value interface{}) {
+	s.Attributes[key] = value
+}
+
+// RecordError RecordError
+func (s *RecordedSpan) RecordError(err error) {
+	s.Err = err
+}
+
+// End End
+func (s *RecordedSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans = append(s.recorder.spans, s)
+}
+
+// Metrics receives the outcome of every statement
+type Metrics interface {
+	ObserveQuery(entity, operation string, duration time.Duration, err error)
+}
+
+// DefaultBuckets are the latency buckets in seconds used by NewPrometheusMetrics
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics counts the statements, their errors and their latency
+// per entity and operation and exposes them in the Prometheus text format,
+// it may be shared by the repos of several entities
+type PrometheusMetrics struct {
+	mu      sync.Mutex
+	buckets []float64
+	series  map[metricsKey]*metricsSeries
+}
+
+type metricsKey struct {
+	entity    string
+	operation string
+}
+
+type metricsSeries struct {
+	count   uint64
+	errors  uint64
+	sum     float64
+	buckets []uint64
+}
+
+// NewPrometheusMetrics returns a PrometheusMetrics with the latency buckets
+// in seconds, DefaultBuckets when none are given
+func NewPrometheusMetrics(buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &PrometheusMetrics{
+		buckets: buckets,
+		series:  make(map[metricsKey]*metricsSeries),
+	}
+}
+
+// ObserveQuery ObserveQuery
+func (m *PrometheusMetrics) ObserveQuery(entity, operation string, duration time.Duration, err error) {
+	seconds := duration.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := metricsKey{entity: entity, operation: operation}
+	series, ok := m.series[key]
+	if !ok {
+		series = &metricsSeries{buckets: make([]uint64, len(m.buckets))}
+		m.series[key] = series
+	}
+	series.count++
+	series.sum += seconds
+	if err != nil {
+		series.errors++
+	}
+	for i, bucket := range m.buckets {
+		if bucket >= seconds {
+			series.buckets[i]++
+		}
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text format
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	keys := make([]metricsKey, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].entity != keys[j].entity {
+			return keys[i].entity < keys[j].entity
+		}
+		return keys[i].operation < keys[j].operation
+	})
+	var buf strings.Builder
+	buf.WriteString("# HELP gorm_queries_total Number of statements run.\n# TYPE gorm_queries_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "gorm_queries_total{entity=%q,operation=%q} %d\n", key.entity, key.operation, m.series[key].count)
+	}
+	buf.WriteString("# HELP gorm_query_errors_total Number of statements that failed.\n# TYPE gorm_query_errors_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "gorm_query_errors_total{entity=%q,operation=%q} %d\n", key.entity, key.operation, m.series[key].errors)
+	}
+	buf.WriteString("# HELP gorm_query_duration_seconds Latency of the statements.\n# TYPE gorm_query_duration_seconds histogram\n")
+	for _, key := range keys {
+		series := m.series[key]
+		for i, bucket := range m.buckets {
+			fmt.Fprintf(&buf, "gorm_query_duration_seconds_bucket{entity=%q,operation=%q,le=\"%g\"} %d\n", key.entity, key.operation, bucket, series.buckets[i])
+		}
+		fmt.Fprintf(&buf, "gorm_query_duration_seconds_bucket{entity=%q,operation=%q,le=\"+Inf\"} %d\n", key.entity, key.operation, series.count)
+		fmt.Fprintf(&buf, "gorm_query_duration_seconds_sum{entity=%q,operation=%q} %g\n", key.entity, key.operation, series.sum)
+		fmt.Fprintf(&buf, "gorm_query_duration_seconds_count{entity=%q,operation=%q} %d\n", key.entity, key.operation, series.count)
+	}
+	m.mu.Unlock()
+	n, err := io.WriteString(w, buf.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics to a Prometheus scrape
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+type preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// stmtCache is a LRU of the statements prepared on preparer, the evicted
+// statements being closed once no more in use
+type stmtCache struct {
+	mu       sync.Mutex
+	preparer preparer
+	size     int
+	entries  map[string]*list.Element
+	lru      *list.List
+}
+
+type stmtEntry struct {
+	sqlStr  string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(preparer preparer, size int) *stmtCache {
+	return &stmtCache{
+		preparer: preparer,
+		size:     size,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// acquire returns the statement of sqlStr, preparing it on a miss
+func (c *stmtCache) acquire(ctx context.Context, sqlStr string) (*stmtEntry, error) {
+	if entry := c.lookup(sqlStr); entry != nil {
+		return entry, nil
+	}
+	stmt, err := c.preparer.PrepareContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[sqlStr]; ok {
+		stmt.Close()
+		return c.use(elem), nil
+	}
+	entry := &stmtEntry{sqlStr: sqlStr, stmt: stmt, refs: 1}
+	c.entries[sqlStr] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		evicted := c.lru.Remove(c.lru.Back()).(*stmtEntry)
+		delete(c.entries, evicted.sqlStr)
+		evicted.evicted = true
+		if evicted.refs == 0 {
+			evicted.stmt.Close()
+		}
+	}
+	return entry, nil
+}
+
+func (c *stmtCache) lookup(sqlStr string) *stmtEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[sqlStr]
+	if !ok {
+		return nil
+	}
+	return c.use(elem)
+}
+
+func (c *stmtCache) use(elem *list.Element) *stmtEntry {
+	c.lru.MoveToFront(elem)
+	entry := elem.Value.(*stmtEntry)
+	entry.refs++
+	return entry
+}
+
+func (c *stmtCache) release(entry *stmtEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.refs--
+	if entry.evicted && entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// cacheable reports whether the shape of sqlStr does not depend on its args,
+// the generated in lists and multi-row inserts having one shape per length
+// which would evict the hot statements
+func cacheable(sqlStr string) bool {
+	return !strings.Contains(sqlStr, " in (?") && !strings.Contains(sqlStr, "),(")
+}
+
+// prepared returns the statement of sqlStr from cache bound to db and the
+// func releasing it, a nil statement when there is no cache or sqlStr is
+// not cacheable
+func prepared(ctx context.Context, db Executor, cache *stmtCache, sqlStr string) (*sql.Stmt, func(), error) {
+	if cache == nil || !cacheable(sqlStr) {
+		return nil, func() {}, nil
+	}
+	entry, err := cache.acquire(ctx, sqlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() {
+		cache.release(entry)
+	}
+	if sqlTx, ok := db.(*sql.Tx); ok && cache.preparer != preparer(sqlTx) {
+		// the statement bound to the transaction is a new one on every call
+		txStmt := sqlTx.StmtContext(ctx, entry.stmt)
+		return txStmt, func() {
+			txStmt.Close()
+			release()
+		}, nil
+	}
+	return entry.stmt, release, nil
+}
+
+// Query describes a statement passed through the interceptors, Table
+// listing the joined tables after the table of the repo, Duration
+// and RowsAffected being filled once it has run, RowsAffected counting the
+// scanned rows for queries
+type Query struct {
+	Operation    string
+	Entity       string
+	Table        string
+	SQL          string
+	Args         []interface{}
+	Duration     time.Duration
+	RowsAffected int64
+}
+
+// Invoker runs query
+type Invoker func(ctx context.Context, query *Query) error
+
+// Interceptor wraps the statements of the repo, calling next to run query
+type Interceptor func(ctx context.Context, query *Query, next Invoker) error
+
+// invoke runs do through the interceptors of tx
+func (tx tx) invoke(ctx context.Context, query *Query, do Invoker) error {
+	invoker := func(ctx context.Context, query *Query) error {
+		start := time.Now()
+		err := do(ctx, query)
+		query.Duration = time.Since(start)
+		return err
+	}
+	for i := len(tx.config.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := tx.config.interceptors[i], invoker
+		invoker = func(ctx context.Context, query *Query) error {
+			return interceptor(ctx, query, next)
+		}
+	}
+	return invoker(ctx, query)
+}
+
+// exec runs an ExecContext through the interceptors of tx
+func (tx tx) exec(ctx context.Context, operation string, sqlStr string, args ...interface{}) (sql.Result, error) {
+	query := &Query{Operation: operation, Entity: "User", Table: tx.tableName(), SQL: sqlStr, Args: args}
+	var result sql.Result
+	err := tx.invoke(ctx, query, func(ctx context.Context, query *Query) error {
+		db, stmtCache := tx.writer()
+		stmt, release, err := prepared(ctx, db, stmtCache, query.SQL)
+		if err != nil {
+			return err
+		}
+		defer release()
+		if stmt != nil {
+			result, err = stmt.ExecContext(ctx, query.Args...)
+		} else {
+			result, err = db.ExecContext(ctx, query.SQL, query.Args...)
+		}
+		if err != nil {
+			return err
+		}
+		query.RowsAffected, err = result.RowsAffected()
+		return err
+	})
+	return result, err
+}
+
+// savepoint runs a savepoint statement through the interceptors of tx,
+// bypassing the statement cache as savepoint names are unique
+func (tx tx) savepoint(ctx context.Context, operation string, sqlStr string) error {
+	query := &Query{Operation: operation, Entity: "User", Table: tx.tableName(), SQL: sqlStr}
+	return tx.invoke(ctx, query, func(ctx context.Context, query *Query) error {
+		_, err := tx.db.ExecContext(ctx, query.SQL)
+		return err
+	})
+}
+
+// query runs a QueryContext through the interceptors of tx, calling scan for each row
+func (tx tx) query(ctx context.Context, operation string, sqlStr string, scan func(rows *sql.Rows) error, args ...interface{}) error {
+	return tx.queryTable(ctx, operation, tx.tableName(), sqlStr, scan, args...)
+}
+
+// queryTable is query for the statements reading table rather than the table of tx
+func (tx tx) queryTable(ctx context.Context, operation string, table string, sqlStr string, scan func(rows *sql.Rows) error, args ...interface{}) error {
+	query := &Query{Operation: operation, Entity: "User", Table: table, SQL: sqlStr, Args: args}
+	return tx.invoke(ctx, query, func(ctx context.Context, query *Query) error {
+		db, stmtCache := tx.reader(ctx)
+		stmt, release, err := prepared(ctx, db, stmtCache, query.SQL)
+		if err != nil {
+			return err
+		}
+		defer release()
+		var rows *sql.Rows
+		if stmt != nil {
+			rows, err = stmt.QueryContext(ctx, query.Args...)
+		} else {
+			rows, err = db.QueryContext(ctx, query.SQL, query.Args...)
+		}
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			if err := scan(rows); err != nil {
+				return err
+			}
+			query.RowsAffected++
+		}
+		return rows.Err()
+	})
+}
+
+// Find Find
+func (tx tx) Find(ctx context.Context, filter Filter, opts ...Option) (results []*User, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Find")
+	defer func() {
+		endSpan(span, err)
+	}()
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	tx, err = tx.bindTable(ctx, options.table)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(shards) == 1 {
+		results, err = shards[0].find(ctx, "Find", filter, options)
+	} else {
+		results, err = tx.findShards(ctx, "Find", shards, filter, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, preload := range options.preloads {
+		if err := preload(tx, ctx, results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (tx tx) find(ctx context.Context, operation string, filter Filter, options *options) ([]*User, error) {
+	findSQL := "select user.id,user.name,user.password,user.created_at,user.updated_at,user.version,user.deleted_at from " + tx.from()
+	sortStr := ""
+	if options.sorterBuilder != nil {
+		sortStr = fmt.Sprintf(" order by %s ", options.sorterBuilder.Build())
+	}
+
+	paginate := ""
+	if options.paginate != nil {
+		paginate = fmt.Sprintf(" inner join (select id from %s %s limit ?, ?) tmp on user.id = tmp.id ", tx.from(), sortStr)
+		if filter != nil && filter.Cond() != "" {
+			paginate = fmt.Sprintf(" inner join (select id from %s where %s %s limit ?, ?) tmp on user.id = tmp.id ", tx.from(), filter.Cond(), sortStr)
+		}
+	}
+
+	withLock, err := tx.lockClause(options.lockMode)
+	if err != nil {
+		return nil, err
+	}
+
+	var sqlStr string
+	var args []interface{}
+	if filter == nil || filter.Cond() == "" {
+		sqlStr = fmt.Sprintf(findSQL+"%s%s%s", sortStr, paginate, withLock)
+		if paginate != "" {
+			sqlStr = fmt.Sprintf(findSQL+"%s%s", paginate, withLock)
+		}
+	} else {
+		sqlStr = fmt.Sprintf(findSQL+" where %s%s%s%s", filter.Cond(), sortStr, paginate, withLock)
+		if paginate != "" {
+			sqlStr = fmt.Sprintf(findSQL+" %s%s", paginate, withLock)
+		}
+		args = filter.Args()
+	}
+	if paginate != "" {
+		args = append(append([]interface{}{}, args...), options.paginate.offset, options.paginate.size)
+	}
+	var results []*User
+	err = tx.query(ctx, operation, sqlStr, func(rows *sql.Rows) error {
+		result := &User{}
+		if err := rows.Scan(&result.ID, &result.Name, &result.Password, &result.CreatedAt, &result.UpdatedAt, &result.Version, &result.DeletedAt); err != nil {
+			return err
+		}
+		results = append(results, result)
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// findShards runs find on every shard, merging the rows in the order of the
+// sorter before paginating them
+func (tx tx) findShards(ctx context.Context, operation string, shards []shardTx, filter Filter, options *options) ([]*User, error) {
+	var less func(a, b *User) bool
+	if options.sorterBuilder != nil {
+		var err error
+		if less, err = lessBySorter(options.sorterBuilder, options.paginate != nil); err != nil {
+			return nil, err
+		}
+	}
+	shardOptions := *options
+	if options.paginate != nil {
+		shardOptions.paginate = &paginate{size: int(options.paginate.offset) + options.paginate.size}
+	}
+	var results []*User
+	for _, shard := range shards {
+		shardResults, err := shard.find(ctx, operation, filter, &shardOptions)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, shardResults...)
+	}
+	if less != nil {
+		sort.SliceStable(results, func(i, j int) bool {
+			return less(results[i], results[j])
+		})
+	}
+	if options.paginate != nil {
+		start := int(options.paginate.offset)
+		if start > len(results) {
+			start = len(results)
+		}
+		end := start + options.paginate.size
+		if end > len(results) {
+			end = len(results)
+		}
+		results = results[start:end]
+	}
+	return results, nil
+}
+
+// FindOne FindOne
+func (tx tx) FindOne(ctx context.Context, filter Filter, opts ...Option) (result *User, err error) {
+	ctx, span := tx.config.startSpan(ctx, "FindOne")
+	defer func() {
+		endSpan(span, err)
+	}()
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	tx, err = tx.bindTable(ctx, options.table)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(shards) == 1 {
+		result, err = shards[0].findOne(ctx, filter, options)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		findOptions := *options
+		findOptions.paginate = &paginate{size: 1}
+		if options.paginate != nil {
+			findOptions.paginate.offset = options.paginate.offset
+		}
+		results, err := tx.findShards(ctx, "FindOne", shards, filter, &findOptions)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			return nil, sql.ErrNoRows
+		}
+		result = results[0]
+	}
+	for _, preload := range options.preloads {
+		if err := preload(tx, ctx, []*User{result}); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (tx tx) findOne(ctx context.Context, filter Filter, options *options) (*User, error) {
+	findSQL := "select user.id,user.name,user.password,user.created_at,user.updated_at,user.version,user.deleted_at from " + tx.from()
+
+	sortStr := ""
+	if options.sorterBuilder != nil {
+		sortStr = fmt.Sprintf(" order by %s ", options.sorterBuilder.Build())
+	}
+
+	paginate := ""
+	if options.paginate != nil {
+		paginate = " limit ?, ? "
+	}
+
+	withLock, err := tx.lockClause(options.lockMode)
+	if err != nil {
+		return nil, err
+	}
+
+	var sqlStr string
+	var args []interface{}
+	if filter == nil || filter.Cond() == "" {
+		sqlStr = fmt.Sprintf(findSQL+"%s%s%s", sortStr, paginate, withLock)
+	} else {
+		sqlStr = fmt.Sprintf(findSQL+" where %s%s%s%s", filter.Cond(), sortStr, paginate, withLock)
+		args = filter.Args()
+	}
+	if paginate != "" {
+		args = append(append([]interface{}{}, args...), options.paginate.offset, options.paginate.size)
+	}
+	var result *User
+	err = tx.query(ctx, "FindOne", sqlStr, func(rows *sql.Rows) error {
+		if result != nil {
+			return nil
+		}
+		result = &User{}
+		return rows.Scan(&result.ID, &result.Name, &result.Password, &result.CreatedAt, &result.UpdatedAt, &result.Version, &result.DeletedAt)
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, sql.ErrNoRows
+	}
+	return result, nil
+}
+
+// Count Count
+func (tx tx) Count(ctx context.Context, filter Filter, opts ...Option) (count int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Count")
+	defer func() {
+		endSpan(span, err)
+	}()
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	tx, err = tx.bindTable(ctx, options.table)
+	if err != nil {
+		return 0, err
+	}
+	return tx.sum(filter, func(shard shardTx) (int64, error) {
+		return shard.count(ctx, filter)
+	})
+}
+
+func (tx tx) count(ctx context.Context, filter Filter) (int64, error) {
+	sqlStr := "select count(*) from " + tx.from()
+	var args []interface{}
+	if filter != nil && filter.Cond() != "" {
+		sqlStr = fmt.Sprintf("select count(*) from %s where %s", tx.from(), filter.Cond())
+		args = filter.Args()
+	}
+	var count int64
+	err := tx.query(ctx, "Count", sqlStr, func(rows *sql.Rows) error {
+		return rows.Scan(&count)
+	}, args...)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Delete Delete
+func (tx tx) Delete(ctx context.Context, filter Filter) (rowsAffected int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Delete")
+	defer func() {
+		endSpan(span, err)
+	}()
+	return tx.update(ctx, "Delete", (&options{}).scope(filter), RawUpdater("deleted_at=?", tx.config.now()))
+}
+
+// Restore Restore
+func (tx tx) Restore(ctx context.Context, filter Filter) (rowsAffected int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Restore")
+	defer func() {
+		endSpan(span, err)
+	}()
+	return tx.update(ctx, "Restore", (&options{onlyTrashed: true}).scope(filter), RawUpdater("deleted_at=null"))
+}
+
+// HardDelete HardDelete
+func (tx tx) HardDelete(ctx context.Context, filter Filter) (rowsAffected int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "HardDelete")
+	defer func() {
+		endSpan(span, err)
+	}()
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	return tx.sum(filter, func(shard shardTx) (int64, error) {
+		return shard.deleteRows(ctx, filter)
+	})
+}
+
+func (tx tx) deleteRows(ctx context.Context, filter Filter) (int64, error) {
+	var result sql.Result
+	var err error
+	if filter == nil || filter.Cond() == "" {
+		result, err = tx.exec(ctx, "HardDelete", tx.deleteFrom())
+	} else {
+		sqlStr := fmt.Sprintf("%s where %s", tx.deleteFrom(), filter.Cond())
+		result, err = tx.exec(ctx, "HardDelete", sqlStr, filter.Args()...)
+	}
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// Update Update
+func (tx tx) Update(ctx context.Context, filter Filter, updaters ...Updater) (rowsAffected int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Update")
+	defer func() {
+		endSpan(span, err)
+	}()
+	return tx.update(ctx, "Update", filter, updaters...)
+}
+
+func (tx tx) update(ctx context.Context, operation string, filter Filter, updaters ...Updater) (int64, error) {
+	if len(updaters) == 0 {
+		return 0, errors.New("no updaters")
+	}
+	tx, err := tx.bindTable(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	return tx.sum(filter, func(shard shardTx) (int64, error) {
+		return shard.updateRows(ctx, operation, filter, updaters)
+	})
+}
+
+func (tx tx) updateRows(ctx context.Context, operation string, filter Filter, updaters []Updater) (int64, error) {
+	var result sql.Result
+	var err error
+	updateStrs := make([]string, 0, len(updaters))
+	updateArgs := make([]interface{}, 0, len(updaters))
+	for _, updater := range updaters {
+		updateStrs = append(updateStrs, updater.Set())
+		updateArgs = append(updateArgs, updater.Args()...)
+	}
+	if !hasUpdater(updaters, ColumnUpdatedAt) {
+		updateStrs = append(updateStrs, "updated_at=?")
+		updateArgs = append(updateArgs, tx.config.now())
+	}
+	if filter == nil || filter.Cond() == "" {
+		sqlStr := fmt.Sprintf("update %s set %s", tx.from(), strings.Join(updateStrs, ","))
+		result, err = tx.exec(ctx, operation, sqlStr, updateArgs...)
+	} else {
+		sqlStr := fmt.Sprintf("update %s set %s where %s", tx.from(), strings.Join(updateStrs, ","), filter.Cond())
+		sqlArgs := append(updateArgs, filter.Args()...)
+		result, err = tx.exec(ctx, operation, sqlStr, sqlArgs...)
+	}
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// UpdateByID UpdateByID
+func (tx tx) UpdateByID(ctx context.Context, id int64, version int64, updaters ...Updater) (rowsAffected int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "UpdateByID")
+	defer func() {
+		endSpan(span, err)
+	}()
+	filter := IDEq(id).And(VersionEq(version))
+	rowsAffected, err = tx.update(ctx, "UpdateByID", filter, append(append([]Updater{}, updaters...), IncrVersion(1))...)
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		return 0, ErrStaleObject
+	}
+	return rowsAffected, nil
+}
+
+// Save Save
+func (tx tx) Save(ctx context.Context, obj *User) (rowsAffected int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Save")
+	defer func() {
+		endSpan(span, err)
+	}()
+	obj.UpdatedAt = tx.config.now()
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	shard, err := tx.shardOf(obj)
+	if err != nil {
+		return 0, err
+	}
+	result, err := shard.exec(ctx, "Save", "update "+shard.from()+" set name=?,password=?,created_at=?,updated_at=?,deleted_at=?,version=version+1 where id=? and version=?", obj.Name, Redact(obj.Password), obj.CreatedAt, obj.UpdatedAt, obj.DeletedAt, obj.ID, obj.Version)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err = result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		return 0, ErrStaleObject
+	}
+	obj.Version++
+	return rowsAffected, nil
+}
+
+// setTimestamps fills the auto managed timestamps of obj before it is created
+func (tx tx) setTimestamps(obj *User) {
+	now := tx.config.now()
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = now
+	}
+	if obj.UpdatedAt.IsZero() {
+		obj.UpdatedAt = now
+	}
+}
+
+// Create Create
+func (tx tx) Create(ctx context.Context, obj *User) (id int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "Create")
+	defer func() {
+		endSpan(span, err)
+	}()
+	tx.setTimestamps(obj)
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	shard, err := tx.shardOf(obj)
+	if err != nil {
+		return 0, err
+	}
+	if tx.insertsKey() {
+		if _, err := shard.exec(ctx, "Create", "insert into "+shard.tableName()+"(id,name,password,created_at,updated_at,version,deleted_at) values (?,?,?,?,?,?,?)", obj.ID, obj.Name, Redact(obj.Password), obj.CreatedAt, obj.UpdatedAt, obj.Version, obj.DeletedAt); err != nil {
+			return 0, err
+		}
+		return int64(obj.ID), nil
+	}
+	result, err := shard.exec(ctx, "Create", "insert into "+shard.tableName()+"(name,password,created_at,updated_at,version,deleted_at) values (?,?,?,?,?,?)", obj.Name, Redact(obj.Password), obj.CreatedAt, obj.UpdatedAt, obj.Version, obj.DeletedAt)
+	if err != nil {
+		return 0, err
+	}
+	lastInsertID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return lastInsertID, nil
+}
+
+// BatchCreate BatchCreate
+func (tx tx) BatchCreate(ctx context.Context, objs []*User) (err error) {
+	ctx, span := tx.config.startSpan(ctx, "BatchCreate")
+	defer func() {
+		endSpan(span, err)
+	}()
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return err
+	}
+	var shards []shardTx
+	objsByShard := make(map[*tableShard][]*User)
+	for _, obj := range objs {
+		shard, err := tx.shardOf(obj)
+		if err != nil {
+			return err
+		}
+		if _, ok := objsByShard[shard.shard]; !ok {
+			shards = append(shards, shard)
+		}
+		objsByShard[shard.shard] = append(objsByShard[shard.shard], obj)
+	}
+	for _, shard := range shards {
+		if err := shard.batchCreate(ctx, objsByShard[shard.shard]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx tx) batchCreate(ctx context.Context, objs []*User) error {
+	insertsKey := tx.insertsKey()
+	sqlBaseStr := "insert into " + tx.tableName() + "(name,password,created_at,updated_at,version,deleted_at) values %s"
+	if insertsKey {
+		sqlBaseStr = "insert into " + tx.tableName() + "(id,name,password,created_at,updated_at,version,deleted_at) values %s"
+	}
+	sqlPlaceHolder := make([]string, 0, len(objs))
+	sqlArgs := make([]interface{}, 0, len(objs)*7)
+	for _, obj := range objs {
+		tx.setTimestamps(obj)
+		if insertsKey {
+			sqlPlaceHolder = append(sqlPlaceHolder, "(?,?,?,?,?,?,?)")
+			sqlArgs = append(sqlArgs, obj.ID, obj.Name, Redact(obj.Password), obj.CreatedAt, obj.UpdatedAt, obj.Version, obj.DeletedAt)
+			continue
+		}
+		sqlPlaceHolder = append(sqlPlaceHolder, "(?,?,?,?,?,?)")
+		sqlArgs = append(sqlArgs, obj.Name, Redact(obj.Password), obj.CreatedAt, obj.UpdatedAt, obj.Version, obj.DeletedAt)
+	}
+	sqlStr := fmt.Sprintf(sqlBaseStr, strings.Join(sqlPlaceHolder, ","))
+	if _, err := tx.exec(ctx, "BatchCreate", sqlStr, sqlArgs...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DistinctID DistinctID
+func (tx tx) DistinctID(ctx context.Context, filter Filter) (results []int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "DistinctID")
+	defer func() {
+		endSpan(span, err)
+	}()
+	filter = (&options{}).scope(filter)
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, shard := range shards {
+		sqlStr := "select distinct id from " + shard.from()
+		var args []interface{}
+		if filter != nil && filter.Cond() != "" {
+			sqlStr = fmt.Sprintf("select distinct id from %s where %s", shard.from(), filter.Cond())
+			args = filter.Args()
+		}
+		err := shard.query(ctx, "DistinctID", sqlStr, func(rows *sql.Rows) error {
+			var result int64
+			if err := rows.Scan(&result); err != nil {
+				return err
+			}
+			if len(shards) > 1 {
+				key := fmt.Sprintf("%#v", result)
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+			results = append(results, result)
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// DistinctName DistinctName
+func (tx tx) DistinctName(ctx context.Context, filter Filter) (results []string, err error) {
+	ctx, span := tx.config.startSpan(ctx, "DistinctName")
+	defer func() {
+		endSpan(span, err)
+	}()
+	filter = (&options{}).scope(filter)
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, shard := range shards {
+		sqlStr := "select distinct name from " + shard.from()
+		var args []interface{}
+		if filter != nil && filter.Cond() != "" {
+			sqlStr = fmt.Sprintf("select distinct name from %s where %s", shard.from(), filter.Cond())
+			args = filter.Args()
+		}
+		err := shard.query(ctx, "DistinctName", sqlStr, func(rows *sql.Rows) error {
+			var result string
+			if err := rows.Scan(&result); err != nil {
+				return err
+			}
+			if len(shards) > 1 {
+				key := fmt.Sprintf("%#v", result)
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+			results = append(results, result)
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// DistinctPassword DistinctPassword
+func (tx tx) DistinctPassword(ctx context.Context, filter Filter) (results []string, err error) {
+	ctx, span := tx.config.startSpan(ctx, "DistinctPassword")
+	defer func() {
+		endSpan(span, err)
+	}()
+	filter = (&options{}).scope(filter)
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, shard := range shards {
+		sqlStr := "select distinct password from " + shard.from()
+		var args []interface{}
+		if filter != nil && filter.Cond() != "" {
+			sqlStr = fmt.Sprintf("select distinct password from %s where %s", shard.from(), filter.Cond())
+			args = filter.Args()
+		}
+		err := shard.query(ctx, "DistinctPassword", sqlStr, func(rows *sql.Rows) error {
+			var result string
+			if err := rows.Scan(&result); err != nil {
+				return err
+			}
+			if len(shards) > 1 {
+				key := fmt.Sprintf("%#v", result)
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+			results = append(results, result)
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// DistinctCreatedAt DistinctCreatedAt
+func (tx tx) DistinctCreatedAt(ctx context.Context, filter Filter) (results []time.Time, err error) {
+	ctx, span := tx.config.startSpan(ctx, "DistinctCreatedAt")
+	defer func() {
+		endSpan(span, err)
+	}()
+	filter = (&options{}).scope(filter)
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, shard := range shards {
+		sqlStr := "select distinct created_at from " + shard.from()
+		var args []interface{}
+		if filter != nil && filter.Cond() != "" {
+			sqlStr = fmt.Sprintf("select distinct created_at from %s where %s", shard.from(), filter.Cond())
+			args = filter.Args()
+		}
+		err := shard.query(ctx, "DistinctCreatedAt", sqlStr, func(rows *sql.Rows) error {
+			var result time.Time
+			if err := rows.Scan(&result); err != nil {
+				return err
+			}
+			if len(shards) > 1 {
+				key := fmt.Sprintf("%#v", result)
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+			results = append(results, result)
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// DistinctUpdatedAt DistinctUpdatedAt
+func (tx tx) DistinctUpdatedAt(ctx context.Context, filter Filter) (results []time.Time, err error) {
+	ctx, span := tx.config.startSpan(ctx, "DistinctUpdatedAt")
+	defer func() {
+		endSpan(span, err)
+	}()
+	filter = (&options{}).scope(filter)
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, shard := range shards {
+		sqlStr := "select distinct updated_at from " + shard.from()
+		var args []interface{}
+		if filter != nil && filter.Cond() != "" {
+			sqlStr = fmt.Sprintf("select distinct updated_at from %s where %s", shard.from(), filter.Cond())
+			args = filter.Args()
+		}
+		err := shard.query(ctx, "DistinctUpdatedAt", sqlStr, func(rows *sql.Rows) error {
+			var result time.Time
+			if err := rows.Scan(&result); err != nil {
+				return err
+			}
+			if len(shards) > 1 {
+				key := fmt.Sprintf("%#v", result)
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+			results = append(results, result)
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// DistinctVersion DistinctVersion
+func (tx tx) DistinctVersion(ctx context.Context, filter Filter) (results []int64, err error) {
+	ctx, span := tx.config.startSpan(ctx, "DistinctVersion")
+	defer func() {
+		endSpan(span, err)
+	}()
+	filter = (&options{}).scope(filter)
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, shard := range shards {
+		sqlStr := "select distinct version from " + shard.from()
+		var args []interface{}
+		if filter != nil && filter.Cond() != "" {
+			sqlStr = fmt.Sprintf("select distinct version from %s where %s", shard.from(), filter.Cond())
+			args = filter.Args()
+		}
+		err := shard.query(ctx, "DistinctVersion", sqlStr, func(rows *sql.Rows) error {
+			var result int64
+			if err := rows.Scan(&result); err != nil {
+				return err
+			}
+			if len(shards) > 1 {
+				key := fmt.Sprintf("%#v", result)
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+			results = append(results, result)
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// DistinctDeletedAt DistinctDeletedAt
+func (tx tx) DistinctDeletedAt(ctx context.Context, filter Filter) (results []sql.NullTime, err error) {
+	ctx, span := tx.config.startSpan(ctx, "DistinctDeletedAt")
+	defer func() {
+		endSpan(span, err)
+	}()
+	filter = (&options{}).scope(filter)
+	tx, err = tx.bindTable(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	shards, err := tx.route(filter)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, shard := range shards {
+		sqlStr := "select distinct deleted_at from " + shard.from()
+		var args []interface{}
+		if filter != nil && filter.Cond() != "" {
+			sqlStr = fmt.Sprintf("select distinct deleted_at from %s where %s", shard.from(), filter.Cond())
+			args = filter.Args()
+		}
+		err := shard.query(ctx, "DistinctDeletedAt", sqlStr, func(rows *sql.Rows) error {
+			var result sql.NullTime
+			if err := rows.Scan(&result); err != nil {
+				return err
+			}
+			if len(shards) > 1 {
+				key := fmt.Sprintf("%#v", result)
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+			results = append(results, result)
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// ErrStaleObject ErrStaleObject
+var ErrStaleObject = errors.New("stale object")
+
+// Updater Updater
+type Updater interface {
+	Set() string
+	Args() []interface{}
+}
+
+type updater struct {
+	set  string
+	args []interface{}
+}
+
+func (u *updater) Set() string {
+	return u.set
+}
+
+func (u *updater) Args() []interface{} {
+	return u.args
+}
+
+// hasUpdater reports whether one of the comma separated assignments of
+// updaters sets column, whatever its whitespace, quoting, case or table
+func hasUpdater(updaters []Updater, column Column) bool {
+	for _, updater := range updaters {
+		set := strings.Join(strings.Fields(updater.Set()), "")
+		for _, assignment := range strings.Split(set, ",") {
+			i := strings.Index(assignment, "=")
+			if i < 0 {
+				continue
+			}
+			target := assignment[:i]
+			target = target[strings.LastIndex(target, ".")+1:]
+			if strings.EqualFold(strings.Trim(target, "\x60"), string(column)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RawUpdater RawUpdater
+func RawUpdater(set string, args ...interface{}) Updater {
+	return &updater{
+		set:  set,
+		args: args,
+	}
+}
+
+// Column Column
+type Column string
+
+// Filter Filter
+type Filter interface {
+	Cond() string
+	Args() []interface{}
+}
+
+// JoinableFilter JoinableFilter
+type JoinableFilter interface {
+	Filter
+	Or(...Filter) JoinableFilter
+	And(...Filter) JoinableFilter
+}
+
+type filter struct {
+	cond string
+	args []interface{}
+	and  []Filter
+}
+
+// pinner is implemented by the filters which may pin a column to a single value
+type pinner interface {
+	pinned(column string) (interface{}, bool)
+}
+
+// group parenthesizes the cond of f, so that its ors stay inside the
+// conjunctions it is added to, keeping the keys f pins
+func group(f Filter) Filter {
+	return &filter{
+		cond: fmt.Sprintf("(%s)", f.Cond()),
+		args: f.Args(),
+		and:  []Filter{f},
+	}
+}
+
+// pinned returns the value an Eq of the conjunction pins column to
+func (f *filter) pinned(column string) (interface{}, bool) {
+	for _, and := range f.and {
+		if pinner, ok := and.(pinner); ok {
+			if value, ok := pinner.pinned(column); ok {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (f *filter) Cond() string {
+	return f.cond
+}
+
+func (f *filter) Args() []interface{} {
+	return f.args
+}
+
+func (f *filter) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(f.Args()))
+	conds = append(conds, f.Cond())
+	args = append(args, f.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{f}, ands...),
+	}
+}
+
+func (f *filter) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(f.Args()))
+	conds = append(conds, f.Cond())
+	args = append(args, f.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// Raw Raw
+func Raw(cond string, args ...interface{}) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("(%s)", cond),
+		args: args,
+	}
+}
+
+// Not Not
+func Not(f Filter) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("not (%s)", f.Cond()),
+		args: f.Args(),
+	}
+}
+
+// Subquery Subquery
+type Subquery interface {
+	SQL() string
+	Args() []interface{}
+}
+
+type subquery struct {
+	sql  string
+	args []interface{}
+}
+
+func (q *subquery) SQL() string {
+	return q.sql
+}
+
+func (q *subquery) Args() []interface{} {
+	return q.args
+}
+
+// Exists Exists
+func Exists(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("exists (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// NotExists NotExists
+func NotExists(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("not exists (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+type options struct {
+	table         string
+	sorterBuilder SorterBuilder
+	paginate      *paginate
+	lockMode      LockMode
+	distinct      bool
+	withTrashed   bool
+	onlyTrashed   bool
+	preloads      []func(tx, context.Context, []*User) error
+}
+
+type paginate struct {
+	offset int64
+	size   int
+}
+
+// Option Option
+type Option func(*options)
+
+// WithPaginate WithPaginate
+func WithPaginate(offset int64, size int) Option {
+	return func(o *options) {
+		curPaginate := o.paginate
+		if curPaginate == nil {
+			curPaginate = &paginate{}
+			o.paginate = curPaginate
+		}
+		curPaginate.offset = offset
+		curPaginate.size = size
+	}
+}
+
+// WithSorterBuilder WithSorterBuilder
+func WithSorterBuilder(sorterBuilder SorterBuilder) Option {
+	return func(o *options) {
+		o.sorterBuilder = sorterBuilder
+	}
+}
+
+// WithLock WithLock
+func WithLock() Option {
+	return WithLockMode(LockForUpdate)
+}
+
+// WithLockMode WithLockMode
+func WithLockMode(lockMode LockMode) Option {
+	return func(o *options) {
+		o.lockMode = lockMode
+	}
+}
+
+// LockMode LockMode
+type LockMode int
+
+// LockMode
+const (
+	LockNone LockMode = iota
+	LockForUpdate
+	LockForUpdateNoWait
+	LockForUpdateSkipLocked
+	LockForShare
+	LockForShareNoWait
+	LockForShareSkipLocked
+)
+
+// ErrLockOutsideTx ErrLockOutsideTx
+var ErrLockOutsideTx = errors.New("lock outside tx")
+
+// ErrUnsupportedLockMode ErrUnsupportedLockMode
+var ErrUnsupportedLockMode = errors.New("unsupported lock mode")
+
+// Dialect Dialect
+type Dialect int
+
+// Dialect
+const (
+	DialectMySQL Dialect = iota
+	DialectMySQL57
+)
+
+func (d Dialect) lockClause(lockMode LockMode) (string, error) {
+	if d == DialectMySQL57 {
+		switch lockMode {
+		case LockForUpdate:
+			return " for update ", nil
+		case LockForShare:
+			return " lock in share mode ", nil
+		}
+		return "", ErrUnsupportedLockMode
+	}
+	switch lockMode {
+	case LockForUpdate:
+		return " for update ", nil
+	case LockForUpdateNoWait:
+		return " for update nowait ", nil
+	case LockForUpdateSkipLocked:
+		return " for update skip locked ", nil
+	case LockForShare:
+		return " for share ", nil
+	case LockForShareNoWait:
+		return " for share nowait ", nil
+	case LockForShareSkipLocked:
+		return " for share skip locked ", nil
+	}
+	return "", ErrUnsupportedLockMode
+}
+
+func (d Dialect) system() string {
+	return "mysql"
+}
+
+// retryable reports whether err is a deadlock or lock wait timeout
+func (d Dialect) retryable(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "Error 1213") || strings.Contains(msg, "Error 1205")
+}
+
+func (tx tx) lockClause(lockMode LockMode) (string, error) {
+	if lockMode == LockNone {
+		return "", nil
+	}
+	if !tx.inTx {
+		return "", ErrLockOutsideTx
+	}
+	return tx.config.dialect.lockClause(lockMode)
+}
+
+// WithTrashed WithTrashed
+func WithTrashed() Option {
+	return func(o *options) {
+		o.withTrashed = true
+	}
+}
+
+// OnlyTrashed OnlyTrashed
+func OnlyTrashed() Option {
+	return func(o *options) {
+		o.onlyTrashed = true
+	}
+}
+
+// scope restricts f to the rows visible under o
+func (o *options) scope(f Filter) Filter {
+	if o.withTrashed {
+		return f
+	}
+	scoped := &filter{cond: "user.deleted_at is null"}
+	if o.onlyTrashed {
+		scoped = &filter{cond: "user.deleted_at is not null"}
+	}
+	if f == nil || f.Cond() == "" {
+		return scoped
+	}
+	return scoped.And(group(f))
+}
+
+// WithTable runs the query on the structurally identical table, which must
+// be a plain or schema qualified identifier
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// WithDistinct makes the Select projections select the distinct values, rows
+// selected whole by the primary key being distinct anyway
+func WithDistinct() Option {
+	return func(o *options) {
+		o.distinct = true
+	}
+}
+
+// selectSQL selects column from from, the distinct values only under WithDistinct
+func (o *options) selectSQL(column, from string) string {
+	if o.distinct {
+		return "select distinct " + column + " from " + from
+	}
+	return "select " + column + " from " + from
+}
+
+// WithJoinSorterBuilders WithJoinSorterBuilders
+func WithJoinSorterBuilders(joinSorterBuilders ...JoinableSorterBuilder) Option {
+	return func(o *options) {
+		result := joinSorterBuilders[0]
+		for _, joinSorterBuilder := range joinSorterBuilders[1:] {
+			result = result.Join(joinSorterBuilder)
+		}
+		o.sorterBuilder = result
+	}
+}
+
+// Sorter Sorter
+type Sorter string
+
+// SorterBuilder SorterBuilder
+type SorterBuilder interface {
+	Build() string
+}
+
+// Join Join
+func (s Sorter) Join(sorterBuilders ...SorterBuilder) JoinableSorterBuilder {
+	result := string(s)
+	for _, sorterBuilder := range sorterBuilders {
+		result += "," + sorterBuilder.Build()
+	}
+	return Sorter(result)
+}
+
+// Build Build
+func (s Sorter) Build() string {
+	return string(s)
+}
+
+// JoinableSorterBuilder JoinableSorterBuilder
+type JoinableSorterBuilder interface {
+	SorterBuilder
+	Join(...SorterBuilder) JoinableSorterBuilder
+}
+
+// ID ID
+type ID int64
+
+// Set Set
+func (n ID) Set() string {
+	return "id=?"
+}
+
+// Args Args
+func (n ID) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// ColumnID ColumnID
+const ColumnID Column = "id"
+
+// IncrID IncrID
+func IncrID(delta int64) Updater {
+	return &updater{
+		set:  "id=id+?",
+		args: []interface{}{delta},
+	}
+}
+
+// DecrID DecrID
+func DecrID(delta int64) Updater {
+	return &updater{
+		set:  "id=id-?",
+		args: []interface{}{delta},
+	}
+}
+
+// SetIDToColumn SetIDToColumn
+func SetIDToColumn(column Column) Updater {
+	return &updater{
+		set: fmt.Sprintf("id=%s", column),
+	}
+}
+
+// SetIDExpr SetIDExpr
+func SetIDExpr(expr string, args ...interface{}) Updater {
+	return &updater{
+		set:  fmt.Sprintf("id=%s", expr),
+		args: args,
+	}
+}
+
+// IDEq IDEq
+type IDEq int64
+
+// Cond Cond
+func (n IDEq) Cond() string {
+	return "user.id=?"
+}
+
+func (n IDEq) pinned(column string) (interface{}, bool) {
+	return int64(n), column == "id"
+}
+
+// Args Args
+func (n IDEq) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n IDEq) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDEq) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDNE IDNE
+type IDNE int64
+
+// Cond Cond
+func (n IDNE) Cond() string {
+	return "user.id != ?"
+}
+
+// Args Args
+func (n IDNE) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n IDNE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDNE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDBt IDBt
+type IDBt int64
+
+// Cond Cond
+func (n IDBt) Cond() string {
+	return "user.id>?"
+}
+
+// Args Args
+func (n IDBt) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n IDBt) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDBt) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDLt IDLt
+type IDLt int64
+
+// Cond Cond
+func (n IDLt) Cond() string {
+	return "user.id<?"
+}
+
+// Args Args
+func (n IDLt) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n IDLt) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDLt) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDBE IDBE
+type IDBE int64
+
+// Cond Cond
+func (n IDBE) Cond() string {
+	return "user.id>=?"
+}
+
+// Args Args
+func (n IDBE) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n IDBE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDBE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDLE IDLE
+type IDLE int64
+
+// Cond Cond
+func (n IDLE) Cond() string {
+	return "user.id<=?"
+}
+
+// Args Args
+func (n IDLE) Args() []interface{} {
+	return []interface{}{int64(n)}
+}
+
+// And And
+func (n IDLE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDLE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDIn IDIn
+type IDIn []int64
+
+// Cond Cond
+func (n IDIn) Cond() string {
+	placeHolders := make([]string, 0, len(n))
+	for range n {
+		placeHolders = append(placeHolders, "?")
+	}
+	return fmt.Sprintf("user.id in (%s)", strings.Join(placeHolders, ","))
+}
+
+// Args Args
+func (n IDIn) Args() []interface{} {
+	args := make([]interface{}, 0, len(n))
+	for _, each := range n {
+		args = append(args, each)
+	}
+	return args
+}
+
+// And And
+func (n IDIn) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDIn) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// IDNotIn IDNotIn
+type IDNotIn []int64
+
+// Cond Cond
+func (n IDNotIn) Cond() string {
+	placeHolders := make([]string, 0, len(n))
+	for range n {
+		placeHolders = append(placeHolders, "?")
+	}
+	return fmt.Sprintf("user.id not in (%s)", strings.Join(placeHolders, ","))
+}
+
+// Args Args
+func (n IDNotIn) Args() []interface{} {
+	args := make([]interface{}, 0, len(n))
+	for _, each := range n {
+		args = append(args, each)
+	}
+	return args
+}
+
+// And And
+func (n IDNotIn) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n IDNotIn) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// SelectID selects id from the rows matching filter
+// visible under opts, the distinct values only under WithDistinct, always on
+// user itself as no repo binds a table or a shard to it
+func SelectID(filter Filter, opts ...Option) Subquery {
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	if filter == nil || filter.Cond() == "" {
+		return &subquery{
+			sql: options.selectSQL("user.id", "user"),
+		}
+	}
+	return &subquery{
+		sql:  fmt.Sprintf("%s where %s", options.selectSQL("user.id", "user"), filter.Cond()),
+		args: filter.Args(),
+	}
+}
+
+// IDInSubquery IDInSubquery
+func IDInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.id in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// IDNotInSubquery IDNotInSubquery
+func IDNotInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.id not in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// SortByID SortByID
+func SortByID(asc bool) JoinableSorterBuilder {
+	if asc {
+		return Sorter("user.id asc")
+	}
+	return Sorter("user.id desc")
+}
+
+// Name Name
+type Name string
+
+// Set Set
+func (n Name) Set() string {
+	return "name=?"
+}
+
+// Args Args
+func (n Name) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// ColumnName ColumnName
+const ColumnName Column = "name"
+
+// SetNameToColumn SetNameToColumn
+func SetNameToColumn(column Column) Updater {
+	return &updater{
+		set: fmt.Sprintf("name=%s", column),
+	}
+}
+
+// SetNameExpr SetNameExpr
+func SetNameExpr(expr string, args ...interface{}) Updater {
+	return &updater{
+		set:  fmt.Sprintf("name=%s", expr),
+		args: args,
+	}
+}
+
+// NameEq NameEq
+type NameEq string
+
+// Cond Cond
+func (n NameEq) Cond() string {
+	return "user.name=?"
+}
+
+func (n NameEq) pinned(column string) (interface{}, bool) {
+	return string(n), column == "name"
+}
+
+// Args Args
+func (n NameEq) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// And And
+func (n NameEq) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n NameEq) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// NameNE NameNE
+type NameNE string
+
+// Cond Cond
+func (n NameNE) Cond() string {
+	return "user.name != ?"
+}
+
+// Args Args
+func (n NameNE) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// And And
+func (n NameNE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n NameNE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// NameBt NameBt
+type NameBt string
+
+// Cond Cond
+func (n NameBt) Cond() string {
+	return "user.name>?"
+}
+
+// Args Args
+func (n NameBt) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// And And
+func (n NameBt) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n NameBt) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// NameLt NameLt
+type NameLt string
+
+// Cond Cond
+func (n NameLt) Cond() string {
+	return "user.name<?"
+}
+
+// Args Args
+func (n NameLt) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// And And
+func (n NameLt) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n NameLt) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// NameBE NameBE
+type NameBE string
+
+// Cond Cond
+func (n NameBE) Cond() string {
+	return "user.name>=?"
+}
+
+// Args Args
+func (n NameBE) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// And And
+func (n NameBE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n NameBE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// NameLE NameLE
+type NameLE string
+
+// Cond Cond
+func (n NameLE) Cond() string {
+	return "user.name<=?"
+}
+
+// Args Args
+func (n NameLE) Args() []interface{} {
+	return []interface{}{string(n)}
+}
+
+// And And
+func (n NameLE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n NameLE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// NameIn NameIn
+type NameIn []string
+
+// Cond Cond
+func (n NameIn) Cond() string {
+	placeHolders := make([]string, 0, len(n))
+	for range n {
+		placeHolders = append(placeHolders, "?")
+	}
+	return fmt.Sprintf("user.name in (%s)", strings.Join(placeHolders, ","))
+}
+
+// Args Args
+func (n NameIn) Args() []interface{} {
+	args := make([]interface{}, 0, len(n))
+	for _, each := range n {
+		args = append(args, each)
+	}
+	return args
+}
+
+// And And
+func (n NameIn) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n NameIn) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// NameNotIn NameNotIn
+type NameNotIn []string
+
+// Cond Cond
+func (n NameNotIn) Cond() string {
+	placeHolders := make([]string, 0, len(n))
+	for range n {
+		placeHolders = append(placeHolders, "?")
+	}
+	return fmt.Sprintf("user.name not in (%s)", strings.Join(placeHolders, ","))
+}
+
+// Args Args
+func (n NameNotIn) Args() []interface{} {
+	args := make([]interface{}, 0, len(n))
+	for _, each := range n {
+		args = append(args, each)
+	}
+	return args
+}
+
+// And And
+func (n NameNotIn) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n NameNotIn) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// SelectName selects name from the rows matching filter
+// visible under opts, the distinct values only under WithDistinct, always on
+// user itself as no repo binds a table or a shard to it
+func SelectName(filter Filter, opts ...Option) Subquery {
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	if filter == nil || filter.Cond() == "" {
+		return &subquery{
+			sql: options.selectSQL("user.name", "user"),
+		}
+	}
+	return &subquery{
+		sql:  fmt.Sprintf("%s where %s", options.selectSQL("user.name", "user"), filter.Cond()),
+		args: filter.Args(),
+	}
+}
+
+// NameInSubquery NameInSubquery
+func NameInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.name in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// NameNotInSubquery NameNotInSubquery
+func NameNotInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.name not in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// SortByName SortByName
+func SortByName(asc bool) JoinableSorterBuilder {
+	if asc {
+		return Sorter("user.name asc")
+	}
+	return Sorter("user.name desc")
+}
+
+// Password Password
+type Password string
+
+// Set Set
+func (n Password) Set() string {
+	return "password=?"
+}
+
+// Args Args
+func (n Password) Args() []interface{} {
+	return []interface{}{Redact(string(n))}
+}
+
+// ColumnPassword ColumnPassword
+const ColumnPassword Column = "password"
+
+// SetPasswordToColumn SetPasswordToColumn
+func SetPasswordToColumn(column Column) Updater {
+	return &updater{
+		set: fmt.Sprintf("password=%s", column),
+	}
+}
+
+// SetPasswordExpr SetPasswordExpr
+func SetPasswordExpr(expr string, args ...interface{}) Updater {
+	return &updater{
+		set:  fmt.Sprintf("password=%s", expr),
+		args: redactAll(args),
+	}
+}
+
+// PasswordEq PasswordEq
+type PasswordEq string
+
+// Cond Cond
+func (n PasswordEq) Cond() string {
+	return "user.password=?"
+}
+
+func (n PasswordEq) pinned(column string) (interface{}, bool) {
+	return string(n), column == "password"
+}
+
+// Args Args
+func (n PasswordEq) Args() []interface{} {
+	return []interface{}{Redact(string(n))}
+}
+
+// And And
+func (n PasswordEq) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n PasswordEq) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// PasswordNE PasswordNE
+type PasswordNE string
+
+// Cond Cond
+func (n PasswordNE) Cond() string {
+	return "user.password != ?"
+}
+
+// Args Args
+func (n PasswordNE) Args() []interface{} {
+	return []interface{}{Redact(string(n))}
+}
+
+// And And
+func (n PasswordNE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n PasswordNE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// PasswordBt PasswordBt
+type PasswordBt string
+
+// Cond Cond
+func (n PasswordBt) Cond() string {
+	return "user.password>?"
+}
+
+// Args Args
+func (n PasswordBt) Args() []interface{} {
+	return []interface{}{Redact(string(n))}
+}
+
+// And And
+func (n PasswordBt) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n PasswordBt) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// PasswordLt PasswordLt
+type PasswordLt string
+
+// Cond Cond
+func (n PasswordLt) Cond() string {
+	return "user.password<?"
+}
+
+// Args Args
+func (n PasswordLt) Args() []interface{} {
+	return []interface{}{Redact(string(n))}
+}
+
+// And And
+func (n PasswordLt) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n PasswordLt) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// PasswordBE PasswordBE
+type PasswordBE string
+
+// Cond Cond
+func (n PasswordBE) Cond() string {
+	return "user.password>=?"
+}
+
+// Args Args
+func (n PasswordBE) Args() []interface{} {
+	return []interface{}{Redact(string(n))}
+}
+
+// And And
+func (n PasswordBE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n PasswordBE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// PasswordLE PasswordLE
+type PasswordLE string
+
+// Cond Cond
+func (n PasswordLE) Cond() string {
+	return "user.password<=?"
+}
+
+// Args Args
+func (n PasswordLE) Args() []interface{} {
+	return []interface{}{Redact(string(n))}
+}
+
+// And And
+func (n PasswordLE) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
+	}
+}
+
+// Or Or
+func (n PasswordLE) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
+	}
+}
+
+// PasswordIn PasswordIn
+type PasswordIn []string
+
+// Cond Cond
+func (n PasswordIn) Cond() string {
+	placeHolders := make([]string, 0, len(n))
+	for range n {
+		placeHolders = append(placeHolders, "?")
+	}
+	return fmt.Sprintf("user.password in (%s)", strings.Join(placeHolders, ","))
+}
+
+// Args Args
+func (n PasswordIn) Args() []interface{} {
+	args := make([]interface{}, 0, len(n))
+	for _, each := range n {
+		args = append(args, Redact(each))
+	}
+	return args
 }
 
-func (f *filter) And(ands ...Filter) JoinableFilter {
+// And And
+func (n PasswordIn) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
-	args := make([]interface{}, 0, len(ands)+len(f.Args()))
-	conds = append(conds, f.Cond())
-	args = append(args, f.Args())
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
-func (f *filter) Or(ors ...Filter) JoinableFilter {
+// Or Or
+func (n PasswordIn) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
-	args := make([]interface{}, 0, len(ors)+len(f.Args()))
-	conds = append(conds, f.Cond())
-	args = append(args, f.Args())
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-type options struct {
-	sorterBuilder SorterBuilder
-	paginate      *paginate
-	withLock      bool
-}
+// PasswordNotIn PasswordNotIn
+type PasswordNotIn []string
 
-type paginate struct {
-	offset int64
-	size   int
+// Cond Cond
+func (n PasswordNotIn) Cond() string {
+	placeHolders := make([]string, 0, len(n))
+	for range n {
+		placeHolders = append(placeHolders, "?")
+	}
+	return fmt.Sprintf("user.password not in (%s)", strings.Join(placeHolders, ","))
 }
 
-// Option Option
-type Option func(*options)
-
-// WithPaginate WithPaginate
-func WithPaginate(offset int64, size int) Option {
-	return func(o *options) {
-		curPaginate := o.paginate
-		if curPaginate == nil {
-			curPaginate = &paginate{}
-			o.paginate = curPaginate
-		}
-		curPaginate.offset = offset
-		curPaginate.size = size
+// Args Args
+func (n PasswordNotIn) Args() []interface{} {
+	args := make([]interface{}, 0, len(n))
+	for _, each := range n {
+		args = append(args, Redact(each))
 	}
+	return args
 }
 
-// WithSorterBuilder WithSorterBuilder
-func WithSorterBuilder(sorterBuilder SorterBuilder) Option {
-	return func(o *options) {
-		o.sorterBuilder = sorterBuilder
+// And And
+func (n PasswordNotIn) And(ands ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ands)+1)
+	args := make([]interface{}, 0, len(ands)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, and := range ands {
+		conds = append(conds, and.Cond())
+		args = append(args, and.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
+		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
-func WithLock() Option {
-	return func(o *options) {
-		o.withLock = true
+// Or Or
+func (n PasswordNotIn) Or(ors ...Filter) JoinableFilter {
+	conds := make([]string, 0, len(ors)+1)
+	args := make([]interface{}, 0, len(ors)+len(n.Args()))
+	conds = append(conds, n.Cond())
+	args = append(args, n.Args()...)
+	for _, or := range ors {
+		conds = append(conds, or.Cond())
+		args = append(args, or.Args()...)
+	}
+	return &filter{
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
+		args: args,
 	}
 }
 
-// WithJoinSorterBuilders WithJoinSorterBuilders
-func WithJoinSorterBuilder(joinSorterBuilders ...JoinableSorterBuilder) Option {
-	return func(o *options) {
-		result := joinSorterBuilders[0]
-		for _, joinSorterBuilder := range joinSorterBuilders[1:] {
-			result = result.Join(joinSorterBuilder)
+// SelectPassword selects password from the rows matching filter
+// visible under opts, the distinct values only under WithDistinct, always on
+// user itself as no repo binds a table or a shard to it
+func SelectPassword(filter Filter, opts ...Option) Subquery {
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	if filter == nil || filter.Cond() == "" {
+		return &subquery{
+			sql: options.selectSQL("user.password", "user"),
 		}
-		o.sorterBuilder = result
+	}
+	return &subquery{
+		sql:  fmt.Sprintf("%s where %s", options.selectSQL("user.password", "user"), filter.Cond()),
+		args: filter.Args(),
 	}
 }
 
-// Sorter Sorter
-type Sorter string
+// PasswordInSubquery PasswordInSubquery
+func PasswordInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.password in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
 
-// SorterBuilder SorterBuilder
-type SorterBuilder interface {
-	Build() string
+// PasswordNotInSubquery PasswordNotInSubquery
+func PasswordNotInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.password not in (%s)", q.SQL()),
+		args: q.Args(),
+	}
 }
 
-// Join Join
-func (s Sorter) Join(sorterBuilders ...SorterBuilder) JoinableSorterBuilder {
-	result := string(s)
-	for _, sorterBuilder := range sorterBuilders {
-		result += "," + sorterBuilder.Build()
+// SortByPassword SortByPassword
+func SortByPassword(asc bool) JoinableSorterBuilder {
+	if asc {
+		return Sorter("user.password asc")
 	}
-	return Sorter(result)
+	return Sorter("user.password desc")
 }
 
-// Build Build
-func (s Sorter) Build() string {
-	return string(s)
+// CreatedAt CreatedAt
+type CreatedAt time.Time
+
+// Set Set
+func (n CreatedAt) Set() string {
+	return "created_at=?"
 }
 
-// JoinableSorterBuilder JoinableSorterBuilder
-type JoinableSorterBuilder interface {
-	SorterBuilder
-	Join(...SorterBuilder) JoinableSorterBuilder
+// Args Args
+func (n CreatedAt) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
-// ID ID
-type ID int64
+// ColumnCreatedAt ColumnCreatedAt
+const ColumnCreatedAt Column = "created_at"
 
-// Set Set
-func (n ID) Set() string {
-	return "id=?"
+// SetCreatedAtToColumn SetCreatedAtToColumn
+func SetCreatedAtToColumn(column Column) Updater {
+	return &updater{
+		set: fmt.Sprintf("created_at=%s", column),
+	}
 }
 
-// Arg Arg
-func (n ID) Arg() interface{} {
-	return n
+// SetCreatedAtExpr SetCreatedAtExpr
+func SetCreatedAtExpr(expr string, args ...interface{}) Updater {
+	return &updater{
+		set:  fmt.Sprintf("created_at=%s", expr),
+		args: args,
+	}
 }
 
-// IDEq IDEq
-type IDEq int64
+// CreatedAtEq CreatedAtEq
+type CreatedAtEq time.Time
 
 // Cond Cond
-func (n IDEq) Cond() string {
-	return "id=?"
+func (n CreatedAtEq) Cond() string {
+	return "user.created_at=?"
+}
+
+func (n CreatedAtEq) pinned(column string) (interface{}, bool) {
+	return time.Time(n), column == "created_at"
 }
 
 // Args Args
-func (n IDEq) Args() []interface{} {
-	return []interface{}{n}
+func (n CreatedAtEq) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
 // And And
-func (n IDEq) And(ands ...Filter) JoinableFilter {
+func (n CreatedAtEq) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n IDEq) Or(ors ...Filter) JoinableFilter {
+func (n CreatedAtEq) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// IDNE IDNE
-type IDNE int64
+// CreatedAtNE CreatedAtNE
+type CreatedAtNE time.Time
 
 // Cond Cond
-func (n IDNE) Cond() string {
-	return "id != ?"
+func (n CreatedAtNE) Cond() string {
+	return "user.created_at != ?"
 }
 
 // Args Args
-func (n IDNE) Args() []interface{} {
-	return []interface{}{n}
+func (n CreatedAtNE) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
 // And And
-func (n IDNE) And(ands ...Filter) JoinableFilter {
+func (n CreatedAtNE) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n IDNE) Or(ors ...Filter) JoinableFilter {
+func (n CreatedAtNE) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// IDBt IDBt
-type IDBt int64
+// CreatedAtBt CreatedAtBt
+type CreatedAtBt time.Time
 
 // Cond Cond
-func (n IDBt) Cond() string {
-	return "id>?"
+func (n CreatedAtBt) Cond() string {
+	return "user.created_at>?"
 }
 
 // Args Args
-func (n IDBt) Args() []interface{} {
-	return []interface{}{n}
+func (n CreatedAtBt) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
 // And And
-func (n IDBt) And(ands ...Filter) JoinableFilter {
+func (n CreatedAtBt) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n IDBt) Or(ors ...Filter) JoinableFilter {
+func (n CreatedAtBt) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// IDLt IDLt
-type IDLt int64
+// CreatedAtLt CreatedAtLt
+type CreatedAtLt time.Time
 
 // Cond Cond
-func (n IDLt) Cond() string {
-	return "id<?"
+func (n CreatedAtLt) Cond() string {
+	return "user.created_at<?"
 }
 
 // Args Args
-func (n IDLt) Args() []interface{} {
-	return []interface{}{n}
+func (n CreatedAtLt) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
 // And And
-func (n IDLt) And(ands ...Filter) JoinableFilter {
+func (n CreatedAtLt) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n IDLt) Or(ors ...Filter) JoinableFilter {
+func (n CreatedAtLt) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// IDBE IDBE
-type IDBE int64
+// CreatedAtBE CreatedAtBE
+type CreatedAtBE time.Time
 
 // Cond Cond
-func (n IDBE) Cond() string {
-	return "id>=?"
+func (n CreatedAtBE) Cond() string {
+	return "user.created_at>=?"
 }
 
 // Args Args
-func (n IDBE) Args() []interface{} {
-	return []interface{}{n}
+func (n CreatedAtBE) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
 // And And
-func (n IDBE) And(ands ...Filter) JoinableFilter {
+func (n CreatedAtBE) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n IDBE) Or(ors ...Filter) JoinableFilter {
+func (n CreatedAtBE) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// IDLE IDLE
-type IDLE int64
+// CreatedAtLE CreatedAtLE
+type CreatedAtLE time.Time
 
 // Cond Cond
-func (n IDLE) Cond() string {
-	return "id<=?"
+func (n CreatedAtLE) Cond() string {
+	return "user.created_at<=?"
 }
 
 // Args Args
-func (n IDLE) Args() []interface{} {
-	return []interface{}{n}
+func (n CreatedAtLE) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
 // And And
-func (n IDLE) And(ands ...Filter) JoinableFilter {
+func (n CreatedAtLE) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n IDLE) Or(ors ...Filter) JoinableFilter {
+func (n CreatedAtLE) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// IDIn IDIn
-type IDIn []int64
+// CreatedAtIn CreatedAtIn
+type CreatedAtIn []time.Time
 
 // Cond Cond
-func (n IDIn) Cond() string {
+func (n CreatedAtIn) Cond() string {
 	placeHolders := make([]string, 0, len(n))
 	for range n {
 		placeHolders = append(placeHolders, "?")
 	}
-	return fmt.Sprintf("id in (%s)", strings.Join(placeHolders, ","))
+	return fmt.Sprintf("user.created_at in (%s)", strings.Join(placeHolders, ","))
 }
 
 // Args Args
-func (n IDIn) Args() []interface{} {
+func (n CreatedAtIn) Args() []interface{} {
 	args := make([]interface{}, 0, len(n))
 	for _, each := range n {
 		args = append(args, each)
@@ -698,51 +4303,52 @@ func (n IDIn) Args() []interface{} {
 }
 
 // And And
-func (n IDIn) And(ands ...Filter) JoinableFilter {
+func (n CreatedAtIn) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n IDIn) Or(ors ...Filter) JoinableFilter {
+func (n CreatedAtIn) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// IDNotIn IDNotIn
-type IDNotIn []int64
+// CreatedAtNotIn CreatedAtNotIn
+type CreatedAtNotIn []time.Time
 
 // Cond Cond
-func (n IDNotIn) Cond() string {
+func (n CreatedAtNotIn) Cond() string {
 	placeHolders := make([]string, 0, len(n))
 	for range n {
 		placeHolders = append(placeHolders, "?")
 	}
-	return fmt.Sprintf("id not in (%s)", strings.Join(placeHolders, ","))
+	return fmt.Sprintf("user.created_at not in (%s)", strings.Join(placeHolders, ","))
 }
 
 // Args Args
-func (n IDNotIn) Args() []interface{} {
+func (n CreatedAtNotIn) Args() []interface{} {
 	args := make([]interface{}, 0, len(n))
 	for _, each := range n {
 		args = append(args, each)
@@ -751,342 +4357,407 @@ func (n IDNotIn) Args() []interface{} {
 }
 
 // And And
-func (n IDNotIn) And(ands ...Filter) JoinableFilter {
+func (n CreatedAtNotIn) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n IDNotIn) Or(ors ...Filter) JoinableFilter {
+func (n CreatedAtNotIn) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// SortByID SortByID
-func SortByID(asc bool) JoinableSorterBuilder {
+// SelectCreatedAt selects created_at from the rows matching filter
+// visible under opts, the distinct values only under WithDistinct, always on
+// user itself as no repo binds a table or a shard to it
+func SelectCreatedAt(filter Filter, opts ...Option) Subquery {
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	if filter == nil || filter.Cond() == "" {
+		return &subquery{
+			sql: options.selectSQL("user.created_at", "user"),
+		}
+	}
+	return &subquery{
+		sql:  fmt.Sprintf("%s where %s", options.selectSQL("user.created_at", "user"), filter.Cond()),
+		args: filter.Args(),
+	}
+}
+
+// CreatedAtInSubquery CreatedAtInSubquery
+func CreatedAtInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.created_at in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// CreatedAtNotInSubquery CreatedAtNotInSubquery
+func CreatedAtNotInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.created_at not in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// SortByCreatedAt SortByCreatedAt
+func SortByCreatedAt(asc bool) JoinableSorterBuilder {
 	if asc {
-		return Sorter("id asc")
+		return Sorter("user.created_at asc")
 	}
-	return Sorter("id desc")
+	return Sorter("user.created_at desc")
+}
+
+// UpdatedAt UpdatedAt
+type UpdatedAt time.Time
+
+// Set Set
+func (n UpdatedAt) Set() string {
+	return "updated_at=?"
 }
 
-// Name Name
-type Name string
+// Args Args
+func (n UpdatedAt) Args() []interface{} {
+	return []interface{}{time.Time(n)}
+}
 
-// Set Set
-func (n Name) Set() string {
-	return "name=?"
+// ColumnUpdatedAt ColumnUpdatedAt
+const ColumnUpdatedAt Column = "updated_at"
+
+// SetUpdatedAtToColumn SetUpdatedAtToColumn
+func SetUpdatedAtToColumn(column Column) Updater {
+	return &updater{
+		set: fmt.Sprintf("updated_at=%s", column),
+	}
 }
 
-// Arg Arg
-func (n Name) Arg() interface{} {
-	return n
+// SetUpdatedAtExpr SetUpdatedAtExpr
+func SetUpdatedAtExpr(expr string, args ...interface{}) Updater {
+	return &updater{
+		set:  fmt.Sprintf("updated_at=%s", expr),
+		args: args,
+	}
 }
 
-// NameEq NameEq
-type NameEq string
+// UpdatedAtEq UpdatedAtEq
+type UpdatedAtEq time.Time
 
 // Cond Cond
-func (n NameEq) Cond() string {
-	return "name=?"
+func (n UpdatedAtEq) Cond() string {
+	return "user.updated_at=?"
+}
+
+func (n UpdatedAtEq) pinned(column string) (interface{}, bool) {
+	return time.Time(n), column == "updated_at"
 }
 
 // Args Args
-func (n NameEq) Args() []interface{} {
-	return []interface{}{n}
+func (n UpdatedAtEq) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
 // And And
-func (n NameEq) And(ands ...Filter) JoinableFilter {
+func (n UpdatedAtEq) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n NameEq) Or(ors ...Filter) JoinableFilter {
+func (n UpdatedAtEq) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// NameNE NameNE
-type NameNE string
+// UpdatedAtNE UpdatedAtNE
+type UpdatedAtNE time.Time
 
 // Cond Cond
-func (n NameNE) Cond() string {
-	return "name != ?"
+func (n UpdatedAtNE) Cond() string {
+	return "user.updated_at != ?"
 }
 
 // Args Args
-func (n NameNE) Args() []interface{} {
-	return []interface{}{n}
+func (n UpdatedAtNE) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
 // And And
-func (n NameNE) And(ands ...Filter) JoinableFilter {
+func (n UpdatedAtNE) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n NameNE) Or(ors ...Filter) JoinableFilter {
+func (n UpdatedAtNE) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// NameBt NameBt
-type NameBt string
+// UpdatedAtBt UpdatedAtBt
+type UpdatedAtBt time.Time
 
 // Cond Cond
-func (n NameBt) Cond() string {
-	return "name>?"
+func (n UpdatedAtBt) Cond() string {
+	return "user.updated_at>?"
 }
 
 // Args Args
-func (n NameBt) Args() []interface{} {
-	return []interface{}{n}
+func (n UpdatedAtBt) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
 // And And
-func (n NameBt) And(ands ...Filter) JoinableFilter {
+func (n UpdatedAtBt) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n NameBt) Or(ors ...Filter) JoinableFilter {
+func (n UpdatedAtBt) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// NameLt NameLt
-type NameLt string
+// UpdatedAtLt UpdatedAtLt
+type UpdatedAtLt time.Time
 
 // Cond Cond
-func (n NameLt) Cond() string {
-	return "name<?"
+func (n UpdatedAtLt) Cond() string {
+	return "user.updated_at<?"
 }
 
 // Args Args
-func (n NameLt) Args() []interface{} {
-	return []interface{}{n}
+func (n UpdatedAtLt) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
 // And And
-func (n NameLt) And(ands ...Filter) JoinableFilter {
+func (n UpdatedAtLt) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n NameLt) Or(ors ...Filter) JoinableFilter {
+func (n UpdatedAtLt) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// NameBE NameBE
-type NameBE string
+// UpdatedAtBE UpdatedAtBE
+type UpdatedAtBE time.Time
 
 // Cond Cond
-func (n NameBE) Cond() string {
-	return "name>=?"
+func (n UpdatedAtBE) Cond() string {
+	return "user.updated_at>=?"
 }
 
 // Args Args
-func (n NameBE) Args() []interface{} {
-	return []interface{}{n}
+func (n UpdatedAtBE) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
 // And And
-func (n NameBE) And(ands ...Filter) JoinableFilter {
+func (n UpdatedAtBE) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n NameBE) Or(ors ...Filter) JoinableFilter {
+func (n UpdatedAtBE) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// NameLE NameLE
-type NameLE string
+// UpdatedAtLE UpdatedAtLE
+type UpdatedAtLE time.Time
 
 // Cond Cond
-func (n NameLE) Cond() string {
-	return "name<=?"
+func (n UpdatedAtLE) Cond() string {
+	return "user.updated_at<=?"
 }
 
 // Args Args
-func (n NameLE) Args() []interface{} {
-	return []interface{}{n}
+func (n UpdatedAtLE) Args() []interface{} {
+	return []interface{}{time.Time(n)}
 }
 
 // And And
-func (n NameLE) And(ands ...Filter) JoinableFilter {
+func (n UpdatedAtLE) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n NameLE) Or(ors ...Filter) JoinableFilter {
+func (n UpdatedAtLE) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// NameIn NameIn
-type NameIn []string
+// UpdatedAtIn UpdatedAtIn
+type UpdatedAtIn []time.Time
 
 // Cond Cond
-func (n NameIn) Cond() string {
+func (n UpdatedAtIn) Cond() string {
 	placeHolders := make([]string, 0, len(n))
 	for range n {
 		placeHolders = append(placeHolders, "?")
 	}
-	return fmt.Sprintf("name in (%s)", strings.Join(placeHolders, ","))
+	return fmt.Sprintf("user.updated_at in (%s)", strings.Join(placeHolders, ","))
 }
 
 // Args Args
-func (n NameIn) Args() []interface{} {
+func (n UpdatedAtIn) Args() []interface{} {
 	args := make([]interface{}, 0, len(n))
 	for _, each := range n {
 		args = append(args, each)
@@ -1095,51 +4766,52 @@ func (n NameIn) Args() []interface{} {
 }
 
 // And And
-func (n NameIn) And(ands ...Filter) JoinableFilter {
+func (n UpdatedAtIn) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n NameIn) Or(ors ...Filter) JoinableFilter {
+func (n UpdatedAtIn) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// NameNotIn NameNotIn
-type NameNotIn []string
+// UpdatedAtNotIn UpdatedAtNotIn
+type UpdatedAtNotIn []time.Time
 
 // Cond Cond
-func (n NameNotIn) Cond() string {
+func (n UpdatedAtNotIn) Cond() string {
 	placeHolders := make([]string, 0, len(n))
 	for range n {
 		placeHolders = append(placeHolders, "?")
 	}
-	return fmt.Sprintf("name not in (%s)", strings.Join(placeHolders, ","))
+	return fmt.Sprintf("user.updated_at not in (%s)", strings.Join(placeHolders, ","))
 }
 
 // Args Args
-func (n NameNotIn) Args() []interface{} {
+func (n UpdatedAtNotIn) Args() []interface{} {
 	args := make([]interface{}, 0, len(n))
 	for _, each := range n {
 		args = append(args, each)
@@ -1148,342 +4820,423 @@ func (n NameNotIn) Args() []interface{} {
 }
 
 // And And
-func (n NameNotIn) And(ands ...Filter) JoinableFilter {
+func (n UpdatedAtNotIn) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n NameNotIn) Or(ors ...Filter) JoinableFilter {
+func (n UpdatedAtNotIn) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// SortByName SortByName
-func SortByName(asc bool) JoinableSorterBuilder {
+// SelectUpdatedAt selects updated_at from the rows matching filter
+// visible under opts, the distinct values only under WithDistinct, always on
+// user itself as no repo binds a table or a shard to it
+func SelectUpdatedAt(filter Filter, opts ...Option) Subquery {
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	if filter == nil || filter.Cond() == "" {
+		return &subquery{
+			sql: options.selectSQL("user.updated_at", "user"),
+		}
+	}
+	return &subquery{
+		sql:  fmt.Sprintf("%s where %s", options.selectSQL("user.updated_at", "user"), filter.Cond()),
+		args: filter.Args(),
+	}
+}
+
+// UpdatedAtInSubquery UpdatedAtInSubquery
+func UpdatedAtInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.updated_at in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// UpdatedAtNotInSubquery UpdatedAtNotInSubquery
+func UpdatedAtNotInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.updated_at not in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// SortByUpdatedAt SortByUpdatedAt
+func SortByUpdatedAt(asc bool) JoinableSorterBuilder {
 	if asc {
-		return Sorter("name asc")
+		return Sorter("user.updated_at asc")
 	}
-	return Sorter("name desc")
+	return Sorter("user.updated_at desc")
 }
 
-// Password Password
-type Password string
+// Version Version
+type Version int64
 
 // Set Set
-func (n Password) Set() string {
-	return "password=?"
+func (n Version) Set() string {
+	return "version=?"
 }
 
-// Arg Arg
-func (n Password) Arg() interface{} {
-	return n
+// Args Args
+func (n Version) Args() []interface{} {
+	return []interface{}{int64(n)}
 }
 
-// PasswordEq PasswordEq
-type PasswordEq string
+// ColumnVersion ColumnVersion
+const ColumnVersion Column = "version"
+
+// IncrVersion IncrVersion
+func IncrVersion(delta int64) Updater {
+	return &updater{
+		set:  "version=version+?",
+		args: []interface{}{delta},
+	}
+}
+
+// DecrVersion DecrVersion
+func DecrVersion(delta int64) Updater {
+	return &updater{
+		set:  "version=version-?",
+		args: []interface{}{delta},
+	}
+}
+
+// SetVersionToColumn SetVersionToColumn
+func SetVersionToColumn(column Column) Updater {
+	return &updater{
+		set: fmt.Sprintf("version=%s", column),
+	}
+}
+
+// SetVersionExpr SetVersionExpr
+func SetVersionExpr(expr string, args ...interface{}) Updater {
+	return &updater{
+		set:  fmt.Sprintf("version=%s", expr),
+		args: args,
+	}
+}
+
+// VersionEq VersionEq
+type VersionEq int64
 
 // Cond Cond
-func (n PasswordEq) Cond() string {
-	return "password=?"
+func (n VersionEq) Cond() string {
+	return "user.version=?"
+}
+
+func (n VersionEq) pinned(column string) (interface{}, bool) {
+	return int64(n), column == "version"
 }
 
 // Args Args
-func (n PasswordEq) Args() []interface{} {
-	return []interface{}{n}
+func (n VersionEq) Args() []interface{} {
+	return []interface{}{int64(n)}
 }
 
 // And And
-func (n PasswordEq) And(ands ...Filter) JoinableFilter {
+func (n VersionEq) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n PasswordEq) Or(ors ...Filter) JoinableFilter {
+func (n VersionEq) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// PasswordNE PasswordNE
-type PasswordNE string
+// VersionNE VersionNE
+type VersionNE int64
 
 // Cond Cond
-func (n PasswordNE) Cond() string {
-	return "password != ?"
+func (n VersionNE) Cond() string {
+	return "user.version != ?"
 }
 
 // Args Args
-func (n PasswordNE) Args() []interface{} {
-	return []interface{}{n}
+func (n VersionNE) Args() []interface{} {
+	return []interface{}{int64(n)}
 }
 
 // And And
-func (n PasswordNE) And(ands ...Filter) JoinableFilter {
+func (n VersionNE) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n PasswordNE) Or(ors ...Filter) JoinableFilter {
+func (n VersionNE) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// PasswordBt PasswordBt
-type PasswordBt string
+// VersionBt VersionBt
+type VersionBt int64
 
 // Cond Cond
-func (n PasswordBt) Cond() string {
-	return "password>?"
+func (n VersionBt) Cond() string {
+	return "user.version>?"
 }
 
 // Args Args
-func (n PasswordBt) Args() []interface{} {
-	return []interface{}{n}
+func (n VersionBt) Args() []interface{} {
+	return []interface{}{int64(n)}
 }
 
 // And And
-func (n PasswordBt) And(ands ...Filter) JoinableFilter {
+func (n VersionBt) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n PasswordBt) Or(ors ...Filter) JoinableFilter {
+func (n VersionBt) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// PasswordLt PasswordLt
-type PasswordLt string
+// VersionLt VersionLt
+type VersionLt int64
 
 // Cond Cond
-func (n PasswordLt) Cond() string {
-	return "password<?"
+func (n VersionLt) Cond() string {
+	return "user.version<?"
 }
 
 // Args Args
-func (n PasswordLt) Args() []interface{} {
-	return []interface{}{n}
+func (n VersionLt) Args() []interface{} {
+	return []interface{}{int64(n)}
 }
 
 // And And
-func (n PasswordLt) And(ands ...Filter) JoinableFilter {
+func (n VersionLt) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n PasswordLt) Or(ors ...Filter) JoinableFilter {
+func (n VersionLt) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// PasswordBE PasswordBE
-type PasswordBE string
+// VersionBE VersionBE
+type VersionBE int64
 
 // Cond Cond
-func (n PasswordBE) Cond() string {
-	return "password>=?"
+func (n VersionBE) Cond() string {
+	return "user.version>=?"
 }
 
 // Args Args
-func (n PasswordBE) Args() []interface{} {
-	return []interface{}{n}
+func (n VersionBE) Args() []interface{} {
+	return []interface{}{int64(n)}
 }
 
 // And And
-func (n PasswordBE) And(ands ...Filter) JoinableFilter {
+func (n VersionBE) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n PasswordBE) Or(ors ...Filter) JoinableFilter {
+func (n VersionBE) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// PasswordLE PasswordLE
-type PasswordLE string
+// VersionLE VersionLE
+type VersionLE int64
 
 // Cond Cond
-func (n PasswordLE) Cond() string {
-	return "password<=?"
+func (n VersionLE) Cond() string {
+	return "user.version<=?"
 }
 
 // Args Args
-func (n PasswordLE) Args() []interface{} {
-	return []interface{}{n}
+func (n VersionLE) Args() []interface{} {
+	return []interface{}{int64(n)}
 }
 
 // And And
-func (n PasswordLE) And(ands ...Filter) JoinableFilter {
+func (n VersionLE) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n PasswordLE) Or(ors ...Filter) JoinableFilter {
+func (n VersionLE) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// PasswordIn PasswordIn
-type PasswordIn []string
+// VersionIn VersionIn
+type VersionIn []int64
 
 // Cond Cond
-func (n PasswordIn) Cond() string {
+func (n VersionIn) Cond() string {
 	placeHolders := make([]string, 0, len(n))
 	for range n {
 		placeHolders = append(placeHolders, "?")
 	}
-	return fmt.Sprintf("password in (%s)", strings.Join(placeHolders, ","))
+	return fmt.Sprintf("user.version in (%s)", strings.Join(placeHolders, ","))
 }
 
 // Args Args
-func (n PasswordIn) Args() []interface{} {
+func (n VersionIn) Args() []interface{} {
 	args := make([]interface{}, 0, len(n))
 	for _, each := range n {
 		args = append(args, each)
@@ -1492,51 +5245,52 @@ func (n PasswordIn) Args() []interface{} {
 }
 
 // And And
-func (n PasswordIn) And(ands ...Filter) JoinableFilter {
+func (n VersionIn) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n PasswordIn) Or(ors ...Filter) JoinableFilter {
+func (n VersionIn) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// PasswordNotIn PasswordNotIn
-type PasswordNotIn []string
+// VersionNotIn VersionNotIn
+type VersionNotIn []int64
 
 // Cond Cond
-func (n PasswordNotIn) Cond() string {
+func (n VersionNotIn) Cond() string {
 	placeHolders := make([]string, 0, len(n))
 	for range n {
 		placeHolders = append(placeHolders, "?")
 	}
-	return fmt.Sprintf("password not in (%s)", strings.Join(placeHolders, ","))
+	return fmt.Sprintf("user.version not in (%s)", strings.Join(placeHolders, ","))
 }
 
 // Args Args
-func (n PasswordNotIn) Args() []interface{} {
+func (n VersionNotIn) Args() []interface{} {
 	args := make([]interface{}, 0, len(n))
 	for _, each := range n {
 		args = append(args, each)
@@ -1545,342 +5299,407 @@ func (n PasswordNotIn) Args() []interface{} {
 }
 
 // And And
-func (n PasswordNotIn) And(ands ...Filter) JoinableFilter {
+func (n VersionNotIn) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n PasswordNotIn) Or(ors ...Filter) JoinableFilter {
+func (n VersionNotIn) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// SortByPassword SortByPassword
-func SortByPassword(asc bool) JoinableSorterBuilder {
+// SelectVersion selects version from the rows matching filter
+// visible under opts, the distinct values only under WithDistinct, always on
+// user itself as no repo binds a table or a shard to it
+func SelectVersion(filter Filter, opts ...Option) Subquery {
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	if filter == nil || filter.Cond() == "" {
+		return &subquery{
+			sql: options.selectSQL("user.version", "user"),
+		}
+	}
+	return &subquery{
+		sql:  fmt.Sprintf("%s where %s", options.selectSQL("user.version", "user"), filter.Cond()),
+		args: filter.Args(),
+	}
+}
+
+// VersionInSubquery VersionInSubquery
+func VersionInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.version in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// VersionNotInSubquery VersionNotInSubquery
+func VersionNotInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.version not in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// SortByVersion SortByVersion
+func SortByVersion(asc bool) JoinableSorterBuilder {
 	if asc {
-		return Sorter("password asc")
+		return Sorter("user.version asc")
 	}
-	return Sorter("password desc")
+	return Sorter("user.version desc")
 }
 
-// CreatedAt CreatedAt
-type CreatedAt time.Time
+// DeletedAt DeletedAt
+type DeletedAt sql.NullTime
 
 // Set Set
-func (n CreatedAt) Set() string {
-	return "created_at=?"
+func (n DeletedAt) Set() string {
+	return "deleted_at=?"
 }
 
-// Arg Arg
-func (n CreatedAt) Arg() interface{} {
-	return n
+// Args Args
+func (n DeletedAt) Args() []interface{} {
+	return []interface{}{sql.NullTime(n)}
 }
 
-// CreatedAtEq CreatedAtEq
-type CreatedAtEq time.Time
+// ColumnDeletedAt ColumnDeletedAt
+const ColumnDeletedAt Column = "deleted_at"
+
+// SetDeletedAtToColumn SetDeletedAtToColumn
+func SetDeletedAtToColumn(column Column) Updater {
+	return &updater{
+		set: fmt.Sprintf("deleted_at=%s", column),
+	}
+}
+
+// SetDeletedAtExpr SetDeletedAtExpr
+func SetDeletedAtExpr(expr string, args ...interface{}) Updater {
+	return &updater{
+		set:  fmt.Sprintf("deleted_at=%s", expr),
+		args: args,
+	}
+}
+
+// DeletedAtEq DeletedAtEq
+type DeletedAtEq sql.NullTime
 
 // Cond Cond
-func (n CreatedAtEq) Cond() string {
-	return "created_at=?"
+func (n DeletedAtEq) Cond() string {
+	return "user.deleted_at=?"
+}
+
+func (n DeletedAtEq) pinned(column string) (interface{}, bool) {
+	return sql.NullTime(n), column == "deleted_at"
 }
 
 // Args Args
-func (n CreatedAtEq) Args() []interface{} {
-	return []interface{}{n}
+func (n DeletedAtEq) Args() []interface{} {
+	return []interface{}{sql.NullTime(n)}
 }
 
 // And And
-func (n CreatedAtEq) And(ands ...Filter) JoinableFilter {
+func (n DeletedAtEq) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n CreatedAtEq) Or(ors ...Filter) JoinableFilter {
+func (n DeletedAtEq) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// CreatedAtNE CreatedAtNE
-type CreatedAtNE time.Time
+// DeletedAtNE DeletedAtNE
+type DeletedAtNE sql.NullTime
 
 // Cond Cond
-func (n CreatedAtNE) Cond() string {
-	return "created_at != ?"
+func (n DeletedAtNE) Cond() string {
+	return "user.deleted_at != ?"
 }
 
 // Args Args
-func (n CreatedAtNE) Args() []interface{} {
-	return []interface{}{n}
+func (n DeletedAtNE) Args() []interface{} {
+	return []interface{}{sql.NullTime(n)}
 }
 
 // And And
-func (n CreatedAtNE) And(ands ...Filter) JoinableFilter {
+func (n DeletedAtNE) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n CreatedAtNE) Or(ors ...Filter) JoinableFilter {
+func (n DeletedAtNE) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// CreatedAtBt CreatedAtBt
-type CreatedAtBt time.Time
+// DeletedAtBt DeletedAtBt
+type DeletedAtBt sql.NullTime
 
 // Cond Cond
-func (n CreatedAtBt) Cond() string {
-	return "created_at>?"
+func (n DeletedAtBt) Cond() string {
+	return "user.deleted_at>?"
 }
 
 // Args Args
-func (n CreatedAtBt) Args() []interface{} {
-	return []interface{}{n}
+func (n DeletedAtBt) Args() []interface{} {
+	return []interface{}{sql.NullTime(n)}
 }
 
 // And And
-func (n CreatedAtBt) And(ands ...Filter) JoinableFilter {
+func (n DeletedAtBt) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n CreatedAtBt) Or(ors ...Filter) JoinableFilter {
+func (n DeletedAtBt) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// CreatedAtLt CreatedAtLt
-type CreatedAtLt time.Time
+// DeletedAtLt DeletedAtLt
+type DeletedAtLt sql.NullTime
 
 // Cond Cond
-func (n CreatedAtLt) Cond() string {
-	return "created_at<?"
+func (n DeletedAtLt) Cond() string {
+	return "user.deleted_at<?"
 }
 
 // Args Args
-func (n CreatedAtLt) Args() []interface{} {
-	return []interface{}{n}
+func (n DeletedAtLt) Args() []interface{} {
+	return []interface{}{sql.NullTime(n)}
 }
 
 // And And
-func (n CreatedAtLt) And(ands ...Filter) JoinableFilter {
+func (n DeletedAtLt) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n CreatedAtLt) Or(ors ...Filter) JoinableFilter {
+func (n DeletedAtLt) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// CreatedAtBE CreatedAtBE
-type CreatedAtBE time.Time
+// DeletedAtBE DeletedAtBE
+type DeletedAtBE sql.NullTime
 
 // Cond Cond
-func (n CreatedAtBE) Cond() string {
-	return "created_at>=?"
+func (n DeletedAtBE) Cond() string {
+	return "user.deleted_at>=?"
 }
 
 // Args Args
-func (n CreatedAtBE) Args() []interface{} {
-	return []interface{}{n}
+func (n DeletedAtBE) Args() []interface{} {
+	return []interface{}{sql.NullTime(n)}
 }
 
 // And And
-func (n CreatedAtBE) And(ands ...Filter) JoinableFilter {
+func (n DeletedAtBE) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n CreatedAtBE) Or(ors ...Filter) JoinableFilter {
+func (n DeletedAtBE) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// CreatedAtLE CreatedAtLE
-type CreatedAtLE time.Time
+// DeletedAtLE DeletedAtLE
+type DeletedAtLE sql.NullTime
 
 // Cond Cond
-func (n CreatedAtLE) Cond() string {
-	return "created_at<=?"
+func (n DeletedAtLE) Cond() string {
+	return "user.deleted_at<=?"
 }
 
 // Args Args
-func (n CreatedAtLE) Args() []interface{} {
-	return []interface{}{n}
+func (n DeletedAtLE) Args() []interface{} {
+	return []interface{}{sql.NullTime(n)}
 }
 
 // And And
-func (n CreatedAtLE) And(ands ...Filter) JoinableFilter {
+func (n DeletedAtLE) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n CreatedAtLE) Or(ors ...Filter) JoinableFilter {
+func (n DeletedAtLE) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// CreatedAtIn CreatedAtIn
-type CreatedAtIn []time.Time
+// DeletedAtIn DeletedAtIn
+type DeletedAtIn []sql.NullTime
 
 // Cond Cond
-func (n CreatedAtIn) Cond() string {
+func (n DeletedAtIn) Cond() string {
 	placeHolders := make([]string, 0, len(n))
 	for range n {
 		placeHolders = append(placeHolders, "?")
 	}
-	return fmt.Sprintf("created_at in (%s)", strings.Join(placeHolders, ","))
+	return fmt.Sprintf("user.deleted_at in (%s)", strings.Join(placeHolders, ","))
 }
 
 // Args Args
-func (n CreatedAtIn) Args() []interface{} {
+func (n DeletedAtIn) Args() []interface{} {
 	args := make([]interface{}, 0, len(n))
 	for _, each := range n {
 		args = append(args, each)
@@ -1889,51 +5708,52 @@ func (n CreatedAtIn) Args() []interface{} {
 }
 
 // And And
-func (n CreatedAtIn) And(ands ...Filter) JoinableFilter {
+func (n DeletedAtIn) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n CreatedAtIn) Or(ors ...Filter) JoinableFilter {
+func (n DeletedAtIn) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// CreatedAtNotIn CreatedAtNotIn
-type CreatedAtNotIn []time.Time
+// DeletedAtNotIn DeletedAtNotIn
+type DeletedAtNotIn []sql.NullTime
 
 // Cond Cond
-func (n CreatedAtNotIn) Cond() string {
+func (n DeletedAtNotIn) Cond() string {
 	placeHolders := make([]string, 0, len(n))
 	for range n {
 		placeHolders = append(placeHolders, "?")
 	}
-	return fmt.Sprintf("created_at not in (%s)", strings.Join(placeHolders, ","))
+	return fmt.Sprintf("user.deleted_at not in (%s)", strings.Join(placeHolders, ","))
 }
 
 // Args Args
-func (n CreatedAtNotIn) Args() []interface{} {
+func (n DeletedAtNotIn) Args() []interface{} {
 	args := make([]interface{}, 0, len(n))
 	for _, each := range n {
 		args = append(args, each)
@@ -1942,41 +5762,78 @@ func (n CreatedAtNotIn) Args() []interface{} {
 }
 
 // And And
-func (n CreatedAtNotIn) And(ands ...Filter) JoinableFilter {
+func (n DeletedAtNotIn) And(ands ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ands)+1)
 	args := make([]interface{}, 0, len(ands)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, and := range ands {
 		conds = append(conds, and.Cond())
-		args = append(args, and.Args())
+		args = append(args, and.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "and")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " and ")),
 		args: args,
+		and:  append([]Filter{n}, ands...),
 	}
 }
 
 // Or Or
-func (n CreatedAtNotIn) Or(ors ...Filter) JoinableFilter {
+func (n DeletedAtNotIn) Or(ors ...Filter) JoinableFilter {
 	conds := make([]string, 0, len(ors)+1)
 	args := make([]interface{}, 0, len(ors)+len(n.Args()))
 	conds = append(conds, n.Cond())
-	args = append(args, n.Args())
+	args = append(args, n.Args()...)
 	for _, or := range ors {
 		conds = append(conds, or.Cond())
-		args = append(args, or.Args())
+		args = append(args, or.Args()...)
 	}
 	return &filter{
-		cond: fmt.Sprintf("(%s)", strings.Join(conds, "or")),
+		cond: fmt.Sprintf("(%s)", strings.Join(conds, " or ")),
 		args: args,
 	}
 }
 
-// SortByCreatedAt SortByCreatedAt
-func SortByCreatedAt(asc bool) JoinableSorterBuilder {
+// SelectDeletedAt selects deleted_at from the rows matching filter
+// visible under opts, the distinct values only under WithDistinct, always on
+// user itself as no repo binds a table or a shard to it
+func SelectDeletedAt(filter Filter, opts ...Option) Subquery {
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	filter = options.scope(filter)
+	if filter == nil || filter.Cond() == "" {
+		return &subquery{
+			sql: options.selectSQL("user.deleted_at", "user"),
+		}
+	}
+	return &subquery{
+		sql:  fmt.Sprintf("%s where %s", options.selectSQL("user.deleted_at", "user"), filter.Cond()),
+		args: filter.Args(),
+	}
+}
+
+// DeletedAtInSubquery DeletedAtInSubquery
+func DeletedAtInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.deleted_at in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// DeletedAtNotInSubquery DeletedAtNotInSubquery
+func DeletedAtNotInSubquery(q Subquery) JoinableFilter {
+	return &filter{
+		cond: fmt.Sprintf("user.deleted_at not in (%s)", q.SQL()),
+		args: q.Args(),
+	}
+}
+
+// SortByDeletedAt SortByDeletedAt
+func SortByDeletedAt(asc bool) JoinableSorterBuilder {
 	if asc {
-		return Sorter("created_at asc")
+		return Sorter("user.deleted_at asc")
 	}
-	return Sorter("created_at desc")
+	return Sorter("user.deleted_at desc")
 }
@@ -3,11 +3,15 @@ package testdata
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/wwq1988/gorm/testdata/fakedb"
 )
 
 func TestOp(t *testing.T) {
@@ -24,12 +28,11 @@ func TestOp(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to open db,err: %#v\r\n", err)
 	}
-	now := time.Now()
-	repo := NewRepo(db)
+	now := time.Now().Truncate(time.Second)
+	repo := NewRepo(db, WithClock(func() time.Time { return now }))
 	givenUser := &User{
-		Name:      "user1",
-		Password:  "password1",
-		CreatedAt: now,
+		Name:     "user1",
+		Password: "password1",
 	}
 	id, err := repo.Create(context.Background(), givenUser)
 	if err != nil {
@@ -40,19 +43,16 @@ func TestOp(t *testing.T) {
 	}
 	givenUsers := []*User{
 		&User{
-			Name:      "user2",
-			Password:  "password2",
-			CreatedAt: now,
+			Name:     "user2",
+			Password: "password2",
 		},
 		&User{
-			Name:      "user3",
-			Password:  "password3",
-			CreatedAt: now,
+			Name:     "user3",
+			Password: "password3",
 		},
 		&User{
-			Name:      "user4",
-			Password:  "password4",
-			CreatedAt: now,
+			Name:     "user4",
+			Password: "password4",
 		},
 	}
 
@@ -68,10 +68,21 @@ func TestOp(t *testing.T) {
 		t.Fatalf("Find unexpected user count")
 	}
 
+	gotNames, err := repo.DistinctName(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to DistinctName user,err: %#v\r\n", err)
+	}
+	if len(gotNames) != 4 {
+		t.Fatalf("DistinctName unexpected name count")
+	}
+
 	gotUser, err := repo.FindOne(context.Background(), NameEq("user1"))
 	if err != nil {
 		t.Fatalf("failed to FindOne user,err: %#v\r\n", err)
 	}
+	if !gotUser.CreatedAt.Equal(now) {
+		t.Fatalf("FindOne unexpected CreatedAt")
+	}
 
 	if gotUser.Name != givenUser.Name ||
 		gotUser.Password != givenUser.Password {
@@ -102,6 +113,78 @@ func TestOp(t *testing.T) {
 		gotUser.Password != "password2" {
 	}
 
+	err = repo.InReadOnlyTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		_, err := tx.FindOne(ctx, NameEq("user1"), WithLockMode(LockForShare))
+		return err
+	}, WithIsolation(sql.LevelRepeatableRead))
+	if err != nil {
+		t.Fatalf("failed to InReadOnlyTx,err: %#v\r\n", err)
+	}
+
+	errNested := errors.New("nested")
+	err = repo.InTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		if _, err := tx.Update(ctx, NameEq("user1"), Password("password5")); err != nil {
+			return err
+		}
+		err := tx.InTx(ctx, func(ctx context.Context, tx Tx) error {
+			if _, err := tx.Update(ctx, NameEq("user1"), Password("password6")); err != nil {
+				return err
+			}
+			return errNested
+		})
+		if err != errNested {
+			return err
+		}
+		gotUser, err := tx.FindOne(ctx, NameEq("user1"))
+		if err != nil {
+			return err
+		}
+		if gotUser.Password != "password5" {
+			t.Fatalf("nested InTx unexpected password")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to InTx,err: %#v\r\n", err)
+	}
+	gotUser, err = repo.FindOne(context.Background(), NameEq("user1"))
+	if err != nil {
+		t.Fatalf("failed to FindOne user,err: %#v\r\n", err)
+	}
+
+	sqlTx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to BeginTx,err: %#v\r\n", err)
+	}
+	if _, err := WithTx(sqlTx).Update(context.Background(), NameEq("user1"), Password("password7")); err != nil {
+		t.Fatalf("failed to Update user in adopted tx,err: %#v\r\n", err)
+	}
+	err = NewRepoFromExecutor(sqlTx).InTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		_, err := tx.FindOne(ctx, NameEq("user1"), WithLock())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to InTx in adopted tx,err: %#v\r\n", err)
+	}
+	if err := sqlTx.Rollback(); err != nil {
+		t.Fatalf("failed to Rollback,err: %#v\r\n", err)
+	}
+
+	staleUser := *gotUser
+	gotUser.Password = "password3"
+	if _, err := repo.Save(context.Background(), gotUser); err != nil {
+		t.Fatalf("failed to Save user,err: %#v\r\n", err)
+	}
+	if _, err := repo.Save(context.Background(), &staleUser); err != ErrStaleObject {
+		t.Fatalf("Save unexpected err: %#v\r\n", err)
+	}
+	if _, err := repo.UpdateByID(context.Background(), gotUser.ID, staleUser.Version, Password("password4")); err != ErrStaleObject {
+		t.Fatalf("UpdateByID unexpected err: %#v\r\n", err)
+	}
+	if _, err := repo.UpdateByID(context.Background(), gotUser.ID, gotUser.Version, Password("password4")); err != nil {
+		t.Fatalf("failed to UpdateByID user,err: %#v\r\n", err)
+	}
+
 	rowsAffected, err = repo.Delete(context.Background(), NameEq("user1"))
 	if err != nil {
 		t.Fatalf("failed to Delete user,err: %#v\r\n", err)
@@ -129,4 +212,671 @@ func TestOp(t *testing.T) {
 	if len(gotUsers) != 0 {
 		t.Fatalf("Find unexpected user count")
 	}
+
+	count, err := repo.Count(context.Background(), nil, OnlyTrashed())
+	if err != nil {
+		t.Fatalf("failed to Count user,err: %#v\r\n", err)
+	}
+	if count != 4 {
+		t.Fatalf("Count unexpected user count")
+	}
+
+	rowsAffected, err = repo.Restore(context.Background(), NameEq("user2"))
+	if err != nil {
+		t.Fatalf("failed to Restore user,err: %#v\r\n", err)
+	}
+	if rowsAffected != 1 {
+		t.Fatalf("Restore unexpected rowsAffetced")
+	}
+
+	count, err = repo.Count(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to Count user,err: %#v\r\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("Count unexpected user count")
+	}
+
+	rowsAffected, err = repo.HardDelete(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to HardDelete user,err: %#v\r\n", err)
+	}
+	if rowsAffected != 4 {
+		t.Fatalf("HardDelete unexpected rowsAffetced")
+	}
+}
+
+func TestUpdaters(t *testing.T) {
+	cases := []struct {
+		updater Updater
+		set     string
+		args    []interface{}
+	}{
+		{Password("password1"), "password=?", []interface{}{Redact("password1")}},
+		{IncrID(2), "id=id+?", []interface{}{int64(2)}},
+		{DecrID(3), "id=id-?", []interface{}{int64(3)}},
+		{SetPasswordToColumn(ColumnName), "password=name", nil},
+		{SetNameExpr("concat(name, ?)", "_bak"), "name=concat(name, ?)", []interface{}{"_bak"}},
+		{RawUpdater("id=coalesce(id, ?)+?", 0, 1), "id=coalesce(id, ?)+?", []interface{}{0, 1}},
+	}
+	for _, c := range cases {
+		if c.updater.Set() != c.set {
+			t.Fatalf("unexpected set,got: %s,want: %s", c.updater.Set(), c.set)
+		}
+		if !reflect.DeepEqual(c.updater.Args(), c.args) {
+			t.Fatalf("unexpected args,got: %#v,want: %#v", c.updater.Args(), c.args)
+		}
+	}
+}
+
+func TestUpdateByIDUpdaters(t *testing.T) {
+	repo := NewRepo(fakedb.New().Open())
+	updaters := make([]Updater, 1, 2)
+	updaters[0] = Name("user1")
+	if _, err := repo.UpdateByID(context.Background(), 1, 1, updaters...); err != nil {
+		t.Fatalf("failed to UpdateByID user,err: %#v\r\n", err)
+	}
+	if updaters[:2][1] != nil {
+		t.Fatalf("unexpected updater appended to the caller's updaters: %#v\r\n", updaters[:2][1])
+	}
+}
+
+func TestFilters(t *testing.T) {
+	filter := NameEq("user1").And(Raw("id > ? or id < ?", 10, 1), Not(PasswordIn{"password1", "password2"}))
+	wantCond := "(user.name=? and (id > ? or id < ?) and not (user.password in (?,?)))"
+	if filter.Cond() != wantCond {
+		t.Fatalf("unexpected cond,got: %s,want: %s", filter.Cond(), wantCond)
+	}
+	wantArgs := []interface{}{"user1", 10, 1, Redact("password1"), Redact("password2")}
+	if !reflect.DeepEqual(filter.Args(), wantArgs) {
+		t.Fatalf("unexpected args,got: %#v,want: %#v", filter.Args(), wantArgs)
+	}
+}
+
+func TestSubqueryFilters(t *testing.T) {
+	filter := IDInSubquery(SelectID(NameEq("user1"))).Or(Exists(SelectID(Raw("user.created_at < ?", "2020-01-01"))))
+	wantCond := "(user.id in (select user.id from user where (user.deleted_at is null and (user.name=?))) or exists (select user.id from user where (user.deleted_at is null and ((user.created_at < ?)))))"
+	if filter.Cond() != wantCond {
+		t.Fatalf("unexpected cond,got: %s,want: %s", filter.Cond(), wantCond)
+	}
+	wantArgs := []interface{}{"user1", "2020-01-01"}
+	if !reflect.DeepEqual(filter.Args(), wantArgs) {
+		t.Fatalf("unexpected args,got: %#v,want: %#v", filter.Args(), wantArgs)
+	}
+	wantSQL := "select distinct user.name from user"
+	if sql := SelectName(nil, WithDistinct(), WithTrashed()).SQL(); sql != wantSQL {
+		t.Fatalf("unexpected sql,got: %s,want: %s", sql, wantSQL)
+	}
+}
+
+func TestLockModes(t *testing.T) {
+	if _, err := NewRepo(nil).Find(context.Background(), nil, WithLock()); err != ErrLockOutsideTx {
+		t.Fatalf("Find unexpected err: %#v\r\n", err)
+	}
+	cases := []struct {
+		dialect  Dialect
+		lockMode LockMode
+		clause   string
+		err      error
+	}{
+		{DialectMySQL, LockForUpdateSkipLocked, " for update skip locked ", nil},
+		{DialectMySQL, LockForShareNoWait, " for share nowait ", nil},
+		{DialectMySQL57, LockForShare, " lock in share mode ", nil},
+		{DialectMySQL57, LockForUpdateNoWait, "", ErrUnsupportedLockMode},
+	}
+	for _, c := range cases {
+		tx := tx{config: &config{dialect: c.dialect}, inTx: true}
+		clause, err := tx.lockClause(c.lockMode)
+		if clause != c.clause || err != c.err {
+			t.Fatalf("unexpected lock clause,got: %q %v,want: %q %v", clause, err, c.clause, c.err)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	retryPolicy := &RetryPolicy{
+		MaxAttempts: 5,
+		BaseBackoff: 10 * time.Millisecond,
+		MaxBackoff:  40 * time.Millisecond,
+	}
+	cases := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{1, 5 * time.Millisecond, 10 * time.Millisecond},
+		{2, 10 * time.Millisecond, 20 * time.Millisecond},
+		{4, 20 * time.Millisecond, 40 * time.Millisecond},
+		{45, 20 * time.Millisecond, 40 * time.Millisecond},
+		{100, 20 * time.Millisecond, 40 * time.Millisecond},
+	}
+	for _, c := range cases {
+		backoff := retryPolicy.backoff(c.attempt)
+		if backoff < c.min || backoff > c.max {
+			t.Fatalf("unexpected backoff,attempt: %d,got: %s", c.attempt, backoff)
+		}
+	}
+
+	unbounded := &RetryPolicy{BaseBackoff: time.Second}
+	for _, attempt := range []int{40, 70, 1000} {
+		if backoff := unbounded.backoff(attempt); backoff < maxBackoff/4 {
+			t.Fatalf("unexpected unbounded backoff,attempt: %d,got: %s", attempt, backoff)
+		}
+	}
+
+	if !DialectMySQL.retryable(errors.New("Error 1213: Deadlock found when trying to get lock; try restarting transaction")) {
+		t.Fatalf("deadlock unexpected not retryable")
+	}
+	if !DialectMySQL.retryable(fmt.Errorf("update user: %w", errors.New("Error 1205 (HY000): Lock wait timeout exceeded"))) {
+		t.Fatalf("lock wait timeout unexpected not retryable")
+	}
+	if DialectMySQL.retryable(errors.New("Error 1062: Duplicate entry")) {
+		t.Fatalf("duplicate entry unexpected retryable")
+	}
+}
+
+// recordQueries records the queries passed through the interceptors
+func recordQueries(queries *[]*Query) RepoOption {
+	return WithInterceptors(func(ctx context.Context, query *Query, next Invoker) error {
+		err := next(ctx, query)
+		*queries = append(*queries, query)
+		return err
+	})
+}
+
+func querySQLs(queries []*Query) []string {
+	sqlStrs := make([]string, 0, len(queries))
+	for _, query := range queries {
+		sqlStrs = append(sqlStrs, query.SQL)
+	}
+	return sqlStrs
+}
+
+func TestInterceptors(t *testing.T) {
+	ctx := context.Background()
+	connector := fakedb.New()
+	db := connector.Open()
+	defer db.Close()
+	var calls []string
+	var queries []*Query
+	var errs []error
+	trace := func(name string) Interceptor {
+		return func(ctx context.Context, query *Query, next Invoker) error {
+			calls = append(calls, name)
+			err := next(ctx, query)
+			calls = append(calls, name)
+			return err
+		}
+	}
+	record := func(ctx context.Context, query *Query, next Invoker) error {
+		err := next(ctx, query)
+		queries = append(queries, query)
+		errs = append(errs, err)
+		return err
+	}
+	repo := NewRepo(db, WithInterceptors(trace("outer"), trace("inner")), WithInterceptors(record))
+	if _, err := repo.Update(ctx, NameEq("a"), Name("b")); err != nil {
+		t.Fatalf("unexpected update err:%#v", err)
+	}
+	if !reflect.DeepEqual(calls, []string{"outer", "inner", "inner", "outer"}) {
+		t.Fatalf("unexpected interceptor order,got: %v", calls)
+	}
+	query := queries[0]
+	if query.Operation != "Update" || query.Entity != "User" || query.Table != "user" {
+		t.Fatalf("unexpected query,got: %+v", query)
+	}
+	if query.SQL != "update user set name=?,updated_at=? where user.name=?" {
+		t.Fatalf("unexpected sql,got: %s", query.SQL)
+	}
+	if len(query.Args) != 3 || query.Args[0] != "b" || query.Args[2] != "a" || query.RowsAffected != 1 {
+		t.Fatalf("unexpected query args,got: %+v", query)
+	}
+
+	errExec := errors.New("exec failed")
+	connector.SetErr(errExec)
+	if _, err := repo.HardDelete(ctx, IDEq(1)); err != errExec {
+		t.Fatalf("unexpected hard delete err:%#v", err)
+	}
+	if queries[1].Operation != "HardDelete" || errs[1] != errExec {
+		t.Fatalf("unexpected hard delete query,got: %+v,err: %v", queries[1], errs[1])
+	}
+}
+
+type logEntry struct {
+	level string
+	msg   string
+	attrs map[string]interface{}
+}
+
+type recordLogger struct {
+	entries []*logEntry
+}
+
+func (l *recordLogger) log(level, msg string, args []interface{}) {
+	attrs := make(map[string]interface{}, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		attrs[args[i].(string)] = args[i+1]
+	}
+	l.entries = append(l.entries, &logEntry{level: level, msg: msg, attrs: attrs})
+}
+
+func (l *recordLogger) InfoContext(ctx context.Context, msg string, args ...interface{}) {
+	l.log("info", msg, args)
+}
+
+func (l *recordLogger) WarnContext(ctx context.Context, msg string, args ...interface{}) {
+	l.log("warn", msg, args)
+}
+
+func (l *recordLogger) ErrorContext(ctx context.Context, msg string, args ...interface{}) {
+	l.log("error", msg, args)
+}
+
+func TestLogger(t *testing.T) {
+	ctx := context.Background()
+	connector := fakedb.New()
+	db := connector.Open()
+	defer db.Close()
+	logger := &recordLogger{}
+	repo := NewRepo(db, WithLogger(logger, WithSlowThreshold(time.Hour)))
+	if _, err := repo.Create(ctx, &User{Name: "user1", Password: "secret"}); err != nil {
+		t.Fatalf("unexpected create err:%#v", err)
+	}
+	entry := logger.entries[0]
+	if entry.level != "info" || entry.attrs["operation"] != "Create" || entry.attrs["sql"] != "insert into user(name,password,created_at,updated_at,version,deleted_at) values (?,?,?,?,?,?)" {
+		t.Fatalf("unexpected log entry,got: %+v", entry)
+	}
+	args := entry.attrs["args"].([]interface{})
+	if args[0] != "user1" || args[1] != "[REDACTED]" {
+		t.Fatalf("unexpected log args,got: %v", args)
+	}
+	if fmt.Sprint(Redact("secret")) != "[REDACTED]" || fmt.Sprintf("%#v", PasswordEq("secret").Args()) != "[]interface {}{[REDACTED]}" {
+		t.Fatalf("sensitive arg unexpected printed")
+	}
+	if value, err := Redact("secret").Value(); err != nil || value != "secret" {
+		t.Fatalf("unexpected redacted value,got: %v,err: %v", value, err)
+	}
+
+	errExec := errors.New("exec failed")
+	connector.SetErr(errExec)
+	if _, err := repo.Delete(ctx, IDEq(1)); err != errExec {
+		t.Fatalf("unexpected delete err:%#v", err)
+	}
+	entry = logger.entries[1]
+	if entry.level != "error" || entry.attrs["error"] != errExec {
+		t.Fatalf("unexpected log entry,got: %+v", entry)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	ctx := context.Background()
+	db := fakedb.New().Open()
+	defer db.Close()
+	recorder := &SpanRecorder{}
+	repo := NewRepo(db, WithTracer(recorder))
+	if _, err := repo.Update(ctx, IDEq(1), Name("user1")); err != nil {
+		t.Fatalf("unexpected update err:%#v", err)
+	}
+	errRollback := errors.New("rollback")
+	if err := repo.InTx(ctx, func(ctx context.Context, tx Tx) error { return errRollback }); err != errRollback {
+		t.Fatalf("unexpected tx err:%#v", err)
+	}
+	spans := recorder.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("unexpected spans,got: %d", len(spans))
+	}
+	statement, update := spans[0], spans[1]
+	want := map[string]interface{}{
+		"db.system":        "mysql",
+		"db.operation":     "Update",
+		"db.sql.table":     "user",
+		"db.statement":     "update user set name=?,updated_at=? where user.id=?",
+		"db.rows_affected": int64(1),
+	}
+	if statement.Name != "Update user" || statement.Parent != update || statement.Err != nil || !reflect.DeepEqual(statement.Attributes, want) {
+		t.Fatalf("unexpected statement span,got: %+v", statement)
+	}
+	want = map[string]interface{}{
+		"db.system":    "mysql",
+		"db.operation": "Update",
+	}
+	if update.Name != "User.Update" || update.Parent != nil || update.Err != nil || !reflect.DeepEqual(update.Attributes, want) {
+		t.Fatalf("unexpected update span,got: %+v", update)
+	}
+	inTx := spans[2]
+	if inTx.Name != "User.InTx" || inTx.Attributes["db.operation"] != "InTx" || inTx.Err != errRollback {
+		t.Fatalf("unexpected tx span,got: %+v", inTx)
+	}
+
+	recorder = &SpanRecorder{}
+	repo = NewRepo(db, WithTracer(recorder), WithSharding(ModShardStrategy("id", 2), TableShards(2)))
+	if _, err := repo.Count(ctx, nil); err != nil {
+		t.Fatalf("unexpected count err:%#v", err)
+	}
+	spans = recorder.Spans()
+	if len(spans) != 3 || spans[2].Name != "User.Count" {
+		t.Fatalf("unexpected count spans,got: %d", len(spans))
+	}
+	for i, table := range []string{"user_00", "user_01"} {
+		if spans[i].Name != "Count "+table || spans[i].Parent != spans[2] || spans[i].Attributes["db.sql.table"] != table {
+			t.Fatalf("unexpected shard span,got: %+v", spans[i])
+		}
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	ctx := context.Background()
+	connector := fakedb.New()
+	db := connector.Open()
+	defer db.Close()
+	metrics := NewPrometheusMetrics(0.5, 0.1)
+	repo := NewRepo(db, WithMetrics(metrics))
+	if _, err := repo.Update(ctx, IDEq(1), Name("user1")); err != nil {
+		t.Fatalf("unexpected update err:%#v", err)
+	}
+	errExec := errors.New("exec failed")
+	connector.SetErr(errExec)
+	if _, err := repo.Update(ctx, IDEq(1), Name("user1")); err != errExec {
+		t.Fatalf("unexpected update err:%#v", err)
+	}
+	metrics.ObserveQuery("Order", "Find", 300*time.Millisecond, nil)
+	metrics.ObserveQuery("Order", "Find", 2*time.Second, nil)
+
+	buf := &strings.Builder{}
+	if _, err := metrics.WriteTo(buf); err != nil {
+		t.Fatalf("unexpected write err:%#v", err)
+	}
+	for _, line := range []string{
+		"# TYPE gorm_queries_total counter",
+		`gorm_queries_total{entity="Order",operation="Find"} 2`,
+		`gorm_queries_total{entity="User",operation="Update"} 2`,
+		`gorm_query_errors_total{entity="User",operation="Update"} 1`,
+		"# TYPE gorm_query_duration_seconds histogram",
+		`gorm_query_duration_seconds_bucket{entity="Order",operation="Find",le="0.1"} 0`,
+		`gorm_query_duration_seconds_bucket{entity="Order",operation="Find",le="0.5"} 1`,
+		`gorm_query_duration_seconds_bucket{entity="Order",operation="Find",le="+Inf"} 2`,
+		`gorm_query_duration_seconds_sum{entity="Order",operation="Find"} 2.3`,
+		`gorm_query_duration_seconds_count{entity="User",operation="Update"} 2`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Fatalf("missing line %s,got:\n%s", line, buf.String())
+		}
+	}
+	if strings.Index(buf.String(), `entity="Order"`) > strings.Index(buf.String(), `entity="User"`) {
+		t.Fatalf("unexpected series order,got:\n%s", buf.String())
+	}
+}
+
+func TestStmtCache(t *testing.T) {
+	ctx := context.Background()
+	connector := fakedb.New()
+	db := connector.Open()
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	repo := NewRepo(db, WithStmtCache(2))
+	for i := 0; i < 3; i++ {
+		if _, err := repo.Update(ctx, IDEq(int64(i)), Name("user1")); err != nil {
+			t.Fatalf("unexpected update err:%#v", err)
+		}
+	}
+	updateSQL := "update user set name=?,updated_at=? where user.id=?"
+	if connector.Prepares(updateSQL) != 1 {
+		t.Fatalf("unexpected prepares,got: %d", connector.Prepares(updateSQL))
+	}
+	err := repo.InTx(ctx, func(ctx context.Context, tx Tx) error {
+		_, err := tx.Update(ctx, IDEq(1), Name("user2"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected tx err:%#v", err)
+	}
+	if connector.Prepares(updateSQL) != 1 {
+		t.Fatalf("unexpected prepares in tx,got: %d", connector.Prepares(updateSQL))
+	}
+
+	if _, err := repo.Find(ctx, NameEq("user1")); err != nil {
+		t.Fatalf("unexpected find err:%#v", err)
+	}
+	if connector.Closes() != 0 {
+		t.Fatalf("unexpected closes,got: %d", connector.Closes())
+	}
+	if _, err := repo.Count(ctx, nil); err != nil {
+		t.Fatalf("unexpected count err:%#v", err)
+	}
+	if connector.Closes() != 1 {
+		t.Fatalf("evicted statement unexpected not closed,closes: %d", connector.Closes())
+	}
+	if _, err := repo.Update(ctx, IDEq(1), Name("user1")); err != nil {
+		t.Fatalf("unexpected update err:%#v", err)
+	}
+	if connector.Prepares(updateSQL) != 2 {
+		t.Fatalf("evicted statement unexpected not prepared again,got: %d", connector.Prepares(updateSQL))
+	}
+
+	var queries []*Query
+	repo = NewRepo(db, WithStmtCache(2), recordQueries(&queries))
+	for i := 0; i < 3; i++ {
+		if _, err := repo.Find(ctx, NameEq("user1"), WithPaginate(int64(i*10), 10)); err != nil {
+			t.Fatalf("unexpected find err:%#v", err)
+		}
+		if _, err := repo.Find(ctx, IDIn(make([]int64, i+1))); err != nil {
+			t.Fatalf("unexpected find err:%#v", err)
+		}
+	}
+	if connector.Prepares(queries[0].SQL) != 1 {
+		t.Fatalf("paginated find unexpected not prepared once,got: %d", connector.Prepares(queries[0].SQL))
+	}
+	for _, query := range queries[1:] {
+		if query.Operation == "Find" && strings.Contains(query.SQL, " in (") && connector.Prepares(query.SQL) != 0 {
+			t.Fatalf("in list unexpected prepared,got: %s", query.SQL)
+		}
+	}
+
+	connector = fakedb.New()
+	db = connector.Open()
+	defer db.Close()
+	repo = NewRepo(db, WithStmtCache(1))
+	if _, err := repo.Update(ctx, IDEq(1), Name("user1")); err != nil {
+		t.Fatalf("unexpected update err:%#v", err)
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("unexpected conn err:%#v", err)
+	}
+	err = repo.InTx(ctx, func(ctx context.Context, tx Tx) error {
+		if _, err := tx.Update(ctx, IDEq(1), Name("user2")); err != nil {
+			return err
+		}
+		conn.Close()
+		if _, err := tx.Count(ctx, nil); err != nil {
+			return err
+		}
+		if connector.Closes() != 1 {
+			t.Fatalf("evicted statement unexpected not closed before the commit,closes: %d", connector.Closes())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected tx err:%#v", err)
+	}
+}
+
+func TestReplicas(t *testing.T) {
+	ctx := context.Background()
+	connectors := make([]*fakedb.Connector, 0, 3)
+	dbs := make([]*sql.DB, 0, 3)
+	for i := 0; i < 3; i++ {
+		connector := fakedb.New()
+		db := connector.Open()
+		defer db.Close()
+		connectors = append(connectors, connector)
+		dbs = append(dbs, db)
+	}
+	repo := NewRepoWithReplicas(dbs[0], []Replica{{DB: dbs[1], Weight: 2}, {DB: dbs[2]}})
+	for i := 0; i < 3; i++ {
+		if _, err := repo.Find(ctx, NameEq("user1")); err != nil {
+			t.Fatalf("unexpected find err:%#v", err)
+		}
+	}
+	if _, err := repo.Update(ctx, IDEq(1), Name("user1")); err != nil {
+		t.Fatalf("unexpected update err:%#v", err)
+	}
+	if _, err := repo.Count(ForcePrimary(ctx), nil); err != nil {
+		t.Fatalf("unexpected count err:%#v", err)
+	}
+	err := repo.InTx(ctx, func(ctx context.Context, tx Tx) error {
+		_, err := tx.FindOne(ctx, IDEq(1), WithLock())
+		if err != sql.ErrNoRows {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected tx err:%#v", err)
+	}
+	for i, want := range []int{3, 2, 1} {
+		if len(connectors[i].Executed()) != want {
+			t.Fatalf("unexpected statements on db %d,got: %v,want: %d", i, connectors[i].Executed(), want)
+		}
+	}
+}
+
+func TestSharding(t *testing.T) {
+	ctx := context.Background()
+	db := fakedb.New().Open()
+	defer db.Close()
+	var queries []*Query
+	repo := NewRepo(db, WithSharding(ModShardStrategy("id", 4), TableShards(4)), recordQueries(&queries))
+	if _, err := repo.Update(ctx, NameEq("user1").And(IDEq(5)), Name("user2")); err != nil {
+		t.Fatalf("unexpected update err:%#v", err)
+	}
+	if id, err := repo.Create(ctx, &User{ID: 6, Name: "user6"}); err != nil || id != 6 {
+		t.Fatalf("unexpected create id: %d,err:%#v", id, err)
+	}
+	if _, err := repo.Count(ctx, NameEq("user1")); err != nil {
+		t.Fatalf("unexpected count err:%#v", err)
+	}
+	if _, err := repo.HardDelete(ctx, NameEq("user1")); err != nil {
+		t.Fatalf("unexpected hard delete err:%#v", err)
+	}
+	want := []string{
+		"update user_01 user set name=?,updated_at=? where (user.name=? and user.id=?)",
+		"insert into user_02(id,name,password,created_at,updated_at,version,deleted_at) values (?,?,?,?,?,?,?)",
+	}
+	for i := 0; i < 4; i++ {
+		want = append(want, fmt.Sprintf("select count(*) from user_%02d user where (user.deleted_at is null and (user.name=?))", i))
+	}
+	for i := 0; i < 4; i++ {
+		want = append(want, fmt.Sprintf("delete user from user_%02d user where user.name=?", i))
+	}
+	if got := querySQLs(queries); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected queries,got: %q", got)
+	}
+	if queries[1].Table != "user_02" || queries[1].Args[0] != int64(6) {
+		t.Fatalf("unexpected create query,got: %+v", queries[1])
+	}
+	if _, err := repo.Create(ctx, &User{Name: "user0"}); !errors.Is(err, ErrMissingShardKey) {
+		t.Fatalf("unexpected create err:%#v", err)
+	}
+	if _, err := repo.Find(ctx, nil, WithSorterBuilder(SortByName(true)), WithPaginate(0, 10)); !errors.Is(err, ErrCrossShardQuery) {
+		t.Fatalf("unexpected paginated find err:%#v", err)
+	}
+
+	strategy := ModShardStrategy("id", 4)
+	if strategy.Shard(int64(-1)) != 3 || strategy.Shard(uint8(6)) != 2 {
+		t.Fatalf("unexpected integer shards")
+	}
+	if shard := strategy.Shard("user1"); shard < 0 || shard >= 4 || shard != strategy.Shard("user1") {
+		t.Fatalf("unexpected string shard,got: %d", shard)
+	}
+
+	less, err := lessBySorter(SortByName(true).Join(SortByID(false)), false)
+	if err != nil {
+		t.Fatalf("unexpected sorter err:%#v", err)
+	}
+	if !less(&User{ID: 1, Name: "a"}, &User{ID: 2, Name: "b"}) || !less(&User{ID: 2, Name: "a"}, &User{ID: 1, Name: "a"}) || less(&User{ID: 1, Name: "a"}, &User{ID: 1, Name: "a"}) {
+		t.Fatalf("unexpected merge order")
+	}
+	if _, err := lessBySorter(SortByID(true), true); err != nil {
+		t.Fatalf("unexpected sorter err:%#v", err)
+	}
+	if _, err := lessBySorter(SortByDeletedAt(true), false); !errors.Is(err, ErrCrossShardQuery) {
+		t.Fatalf("unexpected sorter err:%#v", err)
+	}
+}
+
+type tableKey struct{}
+
+func TestDynamicTable(t *testing.T) {
+	ctx := context.Background()
+	db := fakedb.New().Open()
+	defer db.Close()
+	var queries []*Query
+	repo := NewRepo(db, recordQueries(&queries), WithTableResolver(func(ctx context.Context) string {
+		table, _ := ctx.Value(tableKey{}).(string)
+		return table
+	}))
+	if _, err := repo.Find(ctx, NameEq("user1"), WithTable("user_202610")); err != nil {
+		t.Fatalf("unexpected find err:%#v", err)
+	}
+	if _, err := repo.Update(context.WithValue(ctx, tableKey{}, "user_202609"), IDEq(1), Name("user2")); err != nil {
+		t.Fatalf("unexpected update err:%#v", err)
+	}
+	if _, err := repo.Count(ctx, nil); err != nil {
+		t.Fatalf("unexpected count err:%#v", err)
+	}
+	want := []string{
+		"select user.id,user.name,user.password,user.created_at,user.updated_at,user.version,user.deleted_at from user_202610 user where (user.deleted_at is null and (user.name=?))",
+		"update user_202609 user set name=?,updated_at=? where user.id=?",
+		"select count(*) from user where user.deleted_at is null",
+	}
+	if got := querySQLs(queries); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected queries,got: %q", got)
+	}
+	if _, err := repo.Find(ctx, nil, WithTable("logs.user_202610")); err != nil {
+		t.Fatalf("unexpected find err:%#v", err)
+	}
+	if _, err := repo.Find(ctx, nil, WithTable("user where 1=1 --")); !errors.Is(err, ErrInvalidTable) {
+		t.Fatalf("unexpected find err:%#v", err)
+	}
+	if _, err := repo.Update(context.WithValue(ctx, tableKey{}, "user;drop table user"), IDEq(1), Name("user2")); !errors.Is(err, ErrInvalidTable) {
+		t.Fatalf("unexpected update err:%#v", err)
+	}
+	if len(queries) != 4 {
+		t.Fatalf("unexpected queries,got: %q", querySQLs(queries))
+	}
+}
+
+func TestFindOnePaginate(t *testing.T) {
+	ctx := context.Background()
+	db := fakedb.New().Open()
+	defer db.Close()
+	var queries []*Query
+	repo := NewRepo(db, recordQueries(&queries))
+	if _, err := repo.FindOne(ctx, nil, WithPaginate(2, 1)); err != sql.ErrNoRows {
+		t.Fatalf("unexpected find one err:%#v", err)
+	}
+	if _, err := repo.FindOne(ctx, NameEq("user1"), WithPaginate(2, 1), WithSorterBuilder(SortByID(false))); err != sql.ErrNoRows {
+		t.Fatalf("unexpected find one err:%#v", err)
+	}
+	want := []string{
+		"select user.id,user.name,user.password,user.created_at,user.updated_at,user.version,user.deleted_at from user where user.deleted_at is null limit ?, ? ",
+		"select user.id,user.name,user.password,user.created_at,user.updated_at,user.version,user.deleted_at from user where (user.deleted_at is null and (user.name=?)) order by user.id desc  limit ?, ? ",
+	}
+	if got := querySQLs(queries); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected queries,got: %q", got)
+	}
+	if !reflect.DeepEqual(queries[1].Args, []interface{}{"user1", int64(2), 1}) {
+		t.Fatalf("unexpected args,got: %#v", queries[1].Args)
+	}
+}
+
+func TestTxOptions(t *testing.T) {
+	txOptions := &txOptions{}
+	for _, opt := range []TxOption{WithIsolation(sql.LevelSerializable), WithTxOptions(nil), WithReadOnly()} {
+		opt(txOptions)
+	}
+	if txOptions.Isolation != sql.LevelDefault || !txOptions.ReadOnly {
+		t.Fatalf("unexpected tx options,got: %+v", txOptions.TxOptions)
+	}
 }
@@ -3,5 +3,8 @@ CREATE TABLE user (
 	name varchar(100)  NOT NULL DEFAULT '' COMMENT '名字',
 	password varchar(100) NOT NULL DEFAULT '' COMMENT '密码',
 	created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP  COMMENT '创建时间',
+	updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP  COMMENT '更新时间',
+	version bigint(20) NOT NULL DEFAULT 0 COMMENT '版本',
+	deleted_at timestamp NULL DEFAULT NULL COMMENT '删除时间',
 	PRIMARY KEY (id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
\ No newline at end of file
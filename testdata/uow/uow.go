@@ -0,0 +1,12 @@
+package uow
+
+import (
+	"github.com/wwq1988/gorm/testdata"
+	"github.com/wwq1988/gorm/testdata/order"
+)
+
+// UnitOfWork UnitOfWork
+type UnitOfWork struct {
+	Users  testdata.Repo
+	Orders order.Repo
+}
@@ -0,0 +1,40 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/wwq1988/gorm/testdata"
+	"github.com/wwq1988/gorm/testdata/order"
+)
+
+// UnitOfWorkTx UnitOfWorkTx
+type UnitOfWorkTx struct {
+	Users  testdata.Tx
+	Orders order.Tx
+}
+
+// UnitOfWorkHandler UnitOfWorkHandler
+type UnitOfWorkHandler func(ctx context.Context, tx *UnitOfWorkTx) error
+
+// ErrPoolMismatch is returned by InTx when the repos of the unit of work do
+// not run on the same executor
+var ErrPoolMismatch = errors.New("repos on different pools")
+
+// InTx runs handler with every repo of u bound to the same transaction,
+// started with the options, the retries and the span of the InTx of
+// Users on the executor every repo must share
+func (u *UnitOfWork) InTx(ctx context.Context, handler UnitOfWorkHandler, opts ...testdata.TxOption) error {
+	pool := interface{}(u.Users.Executor())
+	if interface{}(u.Orders.Executor()) != pool {
+		return fmt.Errorf("%w: Orders", ErrPoolMismatch)
+	}
+	return u.Users.InSQLTx(ctx, func(ctx context.Context, sqlTx *sql.Tx) error {
+		return handler(ctx, &UnitOfWorkTx{
+			Users:  u.Users.BindTx(sqlTx),
+			Orders: u.Orders.BindTx(sqlTx),
+		})
+	}, opts...)
+}
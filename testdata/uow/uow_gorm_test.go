@@ -0,0 +1,93 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/wwq1988/gorm/testdata"
+	"github.com/wwq1988/gorm/testdata/fakedb"
+	"github.com/wwq1988/gorm/testdata/order"
+)
+
+func TestInTx(t *testing.T) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s",
+		"root",
+		"devilsm8875",
+		"127.0.0.1",
+		3306,
+		"testdata",
+		"parseTime=true",
+	)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("failed to open db,err: %#v\r\n", err)
+	}
+	uow := &UnitOfWork{
+		Users:  testdata.NewRepo(db),
+		Orders: order.NewRepo(db),
+	}
+	errRollback := errors.New("rollback")
+	err = uow.InTx(context.Background(), func(ctx context.Context, tx *UnitOfWorkTx) error {
+		userID, err := tx.Users.Create(ctx, &testdata.User{Name: "uow"})
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Orders.Create(ctx, &order.Order{UserID: userID, Amount: 1}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatalf("InTx unexpected err: %#v\r\n", err)
+	}
+	count, err := uow.Users.Count(context.Background(), testdata.NameEq("uow"), testdata.WithTrashed())
+	if err != nil {
+		t.Fatalf("failed to Count user,err: %#v\r\n", err)
+	}
+	if count != 0 {
+		t.Fatalf("InTx unexpected user count")
+	}
+}
+
+func TestInTxPool(t *testing.T) {
+	ctx := context.Background()
+	db := fakedb.New().Open()
+	defer db.Close()
+	var userQueries []*testdata.Query
+	uow := &UnitOfWork{
+		Users: testdata.NewRepo(db, testdata.WithInterceptors(func(ctx context.Context, query *testdata.Query, next testdata.Invoker) error {
+			err := next(ctx, query)
+			userQueries = append(userQueries, query)
+			return err
+		})),
+		Orders: order.NewRepo(db),
+	}
+	errRollback := errors.New("rollback")
+	attempts := 0
+	retryPolicy := testdata.RetryPolicy{MaxAttempts: 2, Retryable: func(err error) bool { return err == errRollback }}
+	err := uow.InTx(ctx, func(ctx context.Context, tx *UnitOfWorkTx) error {
+		attempts++
+		if _, err := tx.Users.Update(ctx, testdata.IDEq(1), testdata.Name("uow")); err != nil {
+			return err
+		}
+		return errRollback
+	}, testdata.WithRetry(retryPolicy))
+	if err != errRollback || attempts != 2 || len(userQueries) != 2 {
+		t.Fatalf("InTx unexpected err: %#v,attempts: %d,queries: %d", err, attempts, len(userQueries))
+	}
+
+	other := fakedb.New().Open()
+	defer other.Close()
+	uow.Orders = order.NewRepo(other)
+	err = uow.InTx(ctx, func(ctx context.Context, tx *UnitOfWorkTx) error {
+		return nil
+	})
+	if !errors.Is(err, ErrPoolMismatch) {
+		t.Fatalf("InTx unexpected err: %#v", err)
+	}
+}